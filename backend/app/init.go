@@ -2,6 +2,7 @@ package app
 
 import (
 	"log"
+	"time"
 
 	"oneimg/backend/config"
 	"oneimg/backend/database"
@@ -25,12 +26,51 @@ func Init() *System {
 	// 获取数据库实例
 	db := database.GetDB()
 
-	// 初始化图片服务
+	// 初始化图片服务、链接服务和备用格式生成服务
 	services.InitImageService()
+	services.InitLinkService(cfg)
+	services.InitAlternateService(cfg)
+
+	// 初始化人机验证服务
+	services.InitCaptchaService(cfg)
+
+	// 初始化登录防爆破服务
+	services.InitLoginGuard(cfg)
+
+	// 初始化会话管理服务
+	services.InitSessionService(cfg)
+
+	// 初始化上传配额服务
+	services.InitQuotaService(cfg)
+
+	// 初始化单点登录服务
+	services.InitOIDCService(cfg)
+
+	// 初始化密码策略和邀请注册服务
+	services.InitPasswordPolicy(cfg)
+	services.InitInvitationService()
+
+	// 初始化相册、标签和搜索服务
+	services.InitAlbumService()
+	services.InitTagService()
+	services.InitSearchService()
+
+	// 初始化分享链接和签名URL服务
+	services.InitShareService(cfg)
+	services.InitSignedURLService(cfg)
+
+	// 初始化回收站服务（游客图片清理依赖它，需要先初始化）
+	services.InitTrashService(cfg)
+
+	// 初始化游客上传服务
+	services.InitGuestService(cfg)
 
 	// 初始化默认用户
 	InitDefaultUser(cfg, db)
 
+	// 升级旧版本单用户数据
+	MigrateLegacyOwnership(db)
+
 	r := &System{
 		Config:   cfg,
 		Database: db,
@@ -70,6 +110,7 @@ func InitDefaultUser(cfg *config.Config, db *database.Database) {
 	defaultUser := models.User{
 		Username: defaultUsername,
 		Password: hashedPassword,
+		Role:     models.RoleAdmin,
 	}
 
 	result := db.DB.Create(&defaultUser)
@@ -79,3 +120,49 @@ func InitDefaultUser(cfg *config.Config, db *database.Database) {
 
 	log.Printf("默认用户创建成功 - 用户名: %s, 默认密码: %s", defaultUser.Username, defaultPassword)
 }
+
+// MigrateLegacyOwnership 兼容旧版本的数据
+// 旧版本没有角色和图片归属，升级后将最早创建的用户设为管理员，并把无主图片归属给该用户
+// 只在升级后首次启动时执行一次
+func MigrateLegacyOwnership(db *database.Database) {
+	const name = "legacy_ownership"
+	var done int64
+	db.DB.Model(&models.Migration{}).Where("name = ?", name).Count(&done)
+	if done > 0 {
+		return
+	}
+
+	db.DB.Model(&models.User{}).Where("role = ? OR role IS NULL", "").Update("role", models.RoleUploader)
+
+	var adminCount int64
+	db.DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount)
+
+	var owner models.User
+	if adminCount == 0 {
+		if err := db.DB.Order("id ASC").First(&owner).Error; err != nil {
+			// 尚无用户，等下次启动再执行
+			return
+		}
+		if err := db.DB.Model(&owner).Update("role", models.RoleAdmin).Error; err != nil {
+			log.Fatal("设置管理员失败:", err)
+		}
+		log.Printf("已将用户 %s 设为管理员", owner.Username)
+	} else {
+		db.DB.Where("role = ?", models.RoleAdmin).Order("id ASC").First(&owner)
+	}
+
+	// 游客上传的图片（带删除密钥）保持无主状态
+	result := db.DB.Model(&models.Image{}).
+		Where("(user_id = ? OR user_id IS NULL) AND (delete_token = '' OR delete_token IS NULL)", 0).
+		Update("user_id", owner.Id)
+	if result.Error != nil {
+		log.Fatal("迁移图片归属失败:", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已将 %d 张无归属图片分配给用户 %s", result.RowsAffected, owner.Username)
+	}
+
+	if err := db.DB.Create(&models.Migration{Name: name, AppliedAt: time.Now()}).Error; err != nil {
+		log.Fatal("记录迁移状态失败:", err)
+	}
+}
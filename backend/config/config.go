@@ -2,13 +2,22 @@ package config
 
 import (
 	"log"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// QuotaLimit 配额限制，0表示不限制
+type QuotaLimit struct {
+	Bytes  int64 // 总存储字节数
+	Images int64 // 图片总数
+	Daily  int64 // 每日上传数
+}
+
 type Config struct {
 	// 服务器配置
 	Port string
@@ -29,6 +38,13 @@ type Config struct {
 	AllowedTypes []string
 	UploadPath   string
 
+	// 对外地址（不含末尾斜杠），为空时使用请求的地址
+	PublicBaseURL string            // 站点地址，用于分享链接、邀请链接等
+	ImageBaseURLs map[string]string // 按存储驱动配置的图片地址（CDN或独立图片域名）
+
+	// 受信任的反向代理（IP或CIDR），只有来自这些地址的请求才采用 X-Forwarded-* 请求头
+	TrustedProxies []string
+
 	// 默认用户
 	DefaultUser string
 	DefaultPass string
@@ -38,6 +54,84 @@ type Config struct {
 
 	// Session配置
 	SessionSecret string
+	SessionStore  string // memory / database / redis
+	SessionMaxAge time.Duration
+
+	// Redis配置（SessionStore为redis时使用）
+	RedisAddr     string
+	RedisUsername string
+	RedisPassword string
+	RedisDB       int
+
+	// 人机验证配置
+	CaptchaProvider   string // none / pow / remote_pow / hcaptcha / turnstile / siteverify
+	CaptchaSiteKey    string
+	CaptchaSecret     string
+	CaptchaVerifyURL  string
+	CaptchaDifficulty int // 内置POW难度（前导零比特数）
+
+	// 登录防爆破配置
+	LoginMaxIPFailures  int // 同一IP连续失败多少次后锁定
+	LoginLockDuration   time.Duration
+	LoginFailureWindow  time.Duration // 超过该时间未再失败则重新计数
+	LoginBackoffBase    time.Duration
+	LoginBackoffMax     time.Duration
+	LoginAuditRetention time.Duration // 登录审计记录保留时长
+
+	// 两步验证配置
+	TotpIssuer string
+
+	// 各角色的默认配额（键为角色名）
+	RoleQuotas map[string]QuotaLimit
+
+	// 密码策略
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+
+	// 游客匿名上传配置
+	GuestUploadEnabled  bool
+	GuestMaxFileSize    int64         // 游客单个文件大小上限
+	GuestUploadsPerHour int           // 每个IP每小时上传次数
+	GuestBytesPerDay    int64         // 每个IP每天上传总字节数
+	GuestImageTTL       time.Duration // 游客图片保留时长，0表示永久保留
+
+	// 回收站中图片的保留时长，到期后彻底删除，0表示不自动清理
+	TrashRetention time.Duration
+
+	// 签名URL：私有图片可通过带签名和有效期的URL免登录访问
+	SignedURLSecret string        // 为空或使用示例值时不启用签名URL
+	SignedURLTTL    time.Duration // 未指定有效期时的默认值
+	SignedURLMaxTTL time.Duration // 有效期上限
+
+	// 公开图片的浏览器和CDN缓存时长，图片可能改为私有，到期后需要重新验证
+	ImageCacheMaxAge time.Duration
+
+	// 按 Accept 请求头为 WebP 图片提供 AVIF 或 JPEG/PNG 兼容格式，备用格式在后台生成
+	ContentNegotiation bool
+	AVIFEncoder        string // 生成 AVIF 的外部命令，末尾追加输入（PNG）和输出文件路径，设为 off 时不生成
+
+	// 防盗链：按 Referer 限制图片访问，域名支持 *.example.com 通配
+	HotlinkProtection  bool
+	HotlinkAllowed     []string // 为空时除黑名单外都允许，本站始终允许
+	HotlinkDenied      []string // 优先于白名单
+	HotlinkAllowEmpty  bool     // 是否允许没有 Referer 的请求（直接打开、部分App）
+	HotlinkPlaceholder string   // 拦截时返回的替代图片路径，为空时返回403
+
+	// OpenID Connect 单点登录配置，OIDCIssuer 为空时不启用
+	OIDCIssuer        string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        []string
+	OIDCProviderName  string            // 登录页按钮上显示的名称
+	OIDCUsernameClaim string            // 用作用户名的声明
+	OIDCRoleClaim     string            // 用于映射角色的声明（字符串或字符串数组）
+	OIDCRoleMapping   map[string]string // 声明值 -> 角色
+	OIDCDefaultRole   string            // 没有匹配映射时的角色
+	OIDCAutoProvision bool              // 首次登录时自动创建用户
 }
 
 // 设置全局
@@ -69,6 +163,22 @@ func NewConfig() {
 	// 上传文件配置
 	uploadPath := getEnv("UPLOAD_PATH", "./uploads")
 
+	// 对外地址配置，图片地址依次取 IMAGE_BASE_URL_<驱动>、IMAGE_BASE_URL、PUBLIC_BASE_URL
+	publicBaseURL := strings.TrimRight(getEnv("PUBLIC_BASE_URL", ""), "/")
+	imageBaseURL := strings.TrimRight(getEnv("IMAGE_BASE_URL", publicBaseURL), "/")
+	imageBaseURLs := make(map[string]string)
+	for _, driver := range []string{"local"} {
+		imageBaseURLs[driver] = strings.TrimRight(getEnv("IMAGE_BASE_URL_"+strings.ToUpper(driver), imageBaseURL), "/")
+	}
+
+	// 受信任的反向代理，默认只信任本机，设为 none 则不信任任何代理
+	trustedProxies := getList("TRUSTED_PROXIES")
+	if len(trustedProxies) == 0 {
+		trustedProxies = []string{"127.0.0.1", "::1"}
+	} else if len(trustedProxies) == 1 && trustedProxies[0] == "none" {
+		trustedProxies = nil
+	}
+
 	// 默认用户
 	defaultUser := getEnv("DEFAULT_USER", "admin")
 	defaultPass := getEnv("DEFAULT_PASS", "123456")
@@ -78,6 +188,121 @@ func NewConfig() {
 
 	// Session配置
 	sessionSecret := getEnv("SESSION_SECRET", "your-session-secret-key-change-this-in-production")
+	sessionStore := strings.ToLower(getEnv("SESSION_STORE", "database"))
+	sessionMaxAgeHours, _ := strconv.Atoi(getEnv("SESSION_MAX_AGE_HOURS", "24"))
+	if sessionMaxAgeHours < 1 {
+		sessionMaxAgeHours = 24
+	}
+
+	// Redis配置
+	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
+	redisUsername := getEnv("REDIS_USERNAME", "")
+	redisPassword := getEnv("REDIS_PASSWORD", "")
+	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
+
+	// 人机验证配置
+	captchaProvider := strings.ToLower(getEnv("CAPTCHA_PROVIDER", "pow"))
+	captchaSiteKey := getEnv("CAPTCHA_SITE_KEY", "")
+	captchaSecret := getEnv("CAPTCHA_SECRET", "")
+	captchaVerifyURL := getEnv("CAPTCHA_VERIFY_URL", "")
+	captchaDifficulty, _ := strconv.Atoi(getEnv("CAPTCHA_DIFFICULTY", "18"))
+
+	// 登录防爆破配置
+	loginMaxIPFailures, _ := strconv.Atoi(getEnv("LOGIN_MAX_IP_FAILURES", "20"))
+	loginLockMinutes, _ := strconv.Atoi(getEnv("LOGIN_LOCK_MINUTES", "15"))
+	loginFailureWindowMinutes, _ := strconv.Atoi(getEnv("LOGIN_FAILURE_WINDOW_MINUTES", "15"))
+	if loginFailureWindowMinutes < 1 {
+		loginFailureWindowMinutes = 15
+	}
+	loginBackoffBaseSeconds, _ := strconv.Atoi(getEnv("LOGIN_BACKOFF_BASE_SECONDS", "1"))
+	loginBackoffMaxSeconds, _ := strconv.Atoi(getEnv("LOGIN_BACKOFF_MAX_SECONDS", "60"))
+	loginAuditRetentionDays, _ := strconv.Atoi(getEnv("LOGIN_AUDIT_RETENTION_DAYS", "90"))
+	if loginAuditRetentionDays < 1 {
+		loginAuditRetentionDays = 90
+	}
+
+	// 两步验证配置
+	totpIssuer := getEnv("TOTP_ISSUER", "OneImg")
+
+	// 角色默认配额
+	roleQuotas := map[string]QuotaLimit{
+		"admin":    getQuota("ADMIN"),
+		"uploader": getQuota("UPLOADER"),
+		"viewer":   getQuota("VIEWER"),
+	}
+
+	// 密码策略
+	passwordMinLength, _ := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "6"))
+	if passwordMinLength < 6 {
+		passwordMinLength = 6
+	}
+	passwordRequireUpper := getEnv("PASSWORD_REQUIRE_UPPER", "false") == "true"
+	passwordRequireLower := getEnv("PASSWORD_REQUIRE_LOWER", "false") == "true"
+	passwordRequireDigit := getEnv("PASSWORD_REQUIRE_DIGIT", "false") == "true"
+	passwordRequireSymbol := getEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true"
+
+	// 游客匿名上传配置
+	guestUploadEnabled := getEnv("GUEST_UPLOAD_ENABLED", "false") == "true"
+	guestMaxFileSize, _ := strconv.ParseInt(getEnv("GUEST_MAX_FILE_SIZE", "5242880"), 10, 64)
+	guestUploadsPerHour, _ := strconv.Atoi(getEnv("GUEST_UPLOADS_PER_HOUR", "10"))
+	guestBytesPerDay, _ := strconv.ParseInt(getEnv("GUEST_BYTES_PER_DAY", "52428800"), 10, 64)
+	guestImageTTLHours, _ := strconv.Atoi(getEnv("GUEST_IMAGE_TTL_HOURS", "72"))
+
+	// 回收站配置
+	trashRetentionDays, _ := strconv.Atoi(getEnv("TRASH_RETENTION_DAYS", "30"))
+	if trashRetentionDays < 0 {
+		trashRetentionDays = 0
+	}
+
+	// 签名URL配置
+	signedURLSecret := getEnv("SIGNED_URL_SECRET", "")
+	signedURLTTLMinutes, _ := strconv.Atoi(getEnv("SIGNED_URL_TTL_MINUTES", "60"))
+	if signedURLTTLMinutes < 1 {
+		signedURLTTLMinutes = 60
+	}
+	signedURLMaxTTLHours, _ := strconv.Atoi(getEnv("SIGNED_URL_MAX_TTL_HOURS", "168"))
+	if signedURLMaxTTLHours < 1 {
+		signedURLMaxTTLHours = 168
+	}
+
+	// 公开图片缓存时长
+	imageCacheMaxAgeSeconds, _ := strconv.Atoi(getEnv("IMAGE_CACHE_MAX_AGE_SECONDS", "3600"))
+	if imageCacheMaxAgeSeconds < 0 {
+		imageCacheMaxAgeSeconds = 3600
+	}
+
+	// 图片格式协商配置
+	contentNegotiation := getEnv("CONTENT_NEGOTIATION", "false") == "true"
+	avifEncoder := strings.TrimSpace(getEnv("AVIF_ENCODER", "avifenc --speed 6 --min 18 --max 38"))
+	if avifEncoder == "off" {
+		avifEncoder = ""
+	}
+
+	// 防盗链配置
+	hotlinkProtection := getEnv("HOTLINK_PROTECTION", "false") == "true"
+	hotlinkAllowed := getList("HOTLINK_ALLOWED_REFERERS")
+	hotlinkDenied := getList("HOTLINK_DENIED_REFERERS")
+	hotlinkAllowEmpty := getEnv("HOTLINK_ALLOW_EMPTY_REFERER", "true") == "true"
+	hotlinkPlaceholder := getEnv("HOTLINK_PLACEHOLDER", "")
+
+	// OpenID Connect 单点登录配置
+	oidcIssuer := getEnv("OIDC_ISSUER", "")
+	oidcClientID := getEnv("OIDC_CLIENT_ID", "")
+	oidcClientSecret := getEnv("OIDC_CLIENT_SECRET", "")
+	oidcRedirectURL := getEnv("OIDC_REDIRECT_URL", "")
+	oidcProviderName := getEnv("OIDC_PROVIDER_NAME", "SSO")
+	oidcUsernameClaim := getEnv("OIDC_USERNAME_CLAIM", "preferred_username")
+	oidcRoleClaim := getEnv("OIDC_ROLE_CLAIM", "groups")
+	oidcDefaultRole := strings.ToLower(getEnv("OIDC_DEFAULT_ROLE", "uploader"))
+	oidcAutoProvision := getEnv("OIDC_AUTO_PROVISION", "true") == "true"
+	oidcScopes := strings.Fields(strings.ReplaceAll(getEnv("OIDC_SCOPES", "openid profile email"), ",", " "))
+	oidcRoleMapping := make(map[string]string)
+	for _, pair := range strings.Split(getEnv("OIDC_ROLE_MAPPING", ""), ",") {
+		value, role, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && value != "" && role != "" {
+			oidcRoleMapping[value] = strings.ToLower(role)
+		}
+	}
 
 	App = &Config{
 		Port:          port,
@@ -89,13 +314,120 @@ func NewConfig() {
 		DbPassword:    dbPassword,
 		DbName:        dbName,
 		UploadPath:    uploadPath,
+		PublicBaseURL: publicBaseURL,
+		ImageBaseURLs: imageBaseURLs,
 		MaxFileSize:   maxFileSize,
 		AllowedTypes:  allowedTypes,
 		DefaultUser:   defaultUser,
 		DefaultPass:   defaultPass,
 		JWTSecret:     jwtSecret,
 		SessionSecret: sessionSecret,
+		SessionStore:  sessionStore,
+		SessionMaxAge: time.Duration(sessionMaxAgeHours) * time.Hour,
+
+		TrustedProxies: trustedProxies,
+
+		RedisAddr:     redisAddr,
+		RedisUsername: redisUsername,
+		RedisPassword: redisPassword,
+		RedisDB:       redisDB,
+
+		CaptchaProvider:   captchaProvider,
+		CaptchaSiteKey:    captchaSiteKey,
+		CaptchaSecret:     captchaSecret,
+		CaptchaVerifyURL:  captchaVerifyURL,
+		CaptchaDifficulty: captchaDifficulty,
+
+		LoginMaxIPFailures:  loginMaxIPFailures,
+		LoginLockDuration:   time.Duration(loginLockMinutes) * time.Minute,
+		LoginFailureWindow:  time.Duration(loginFailureWindowMinutes) * time.Minute,
+		LoginBackoffBase:    time.Duration(loginBackoffBaseSeconds) * time.Second,
+		LoginBackoffMax:     time.Duration(loginBackoffMaxSeconds) * time.Second,
+		LoginAuditRetention: time.Duration(loginAuditRetentionDays) * 24 * time.Hour,
+
+		TotpIssuer: totpIssuer,
+
+		RoleQuotas: roleQuotas,
+
+		PasswordMinLength:     passwordMinLength,
+		PasswordRequireUpper:  passwordRequireUpper,
+		PasswordRequireLower:  passwordRequireLower,
+		PasswordRequireDigit:  passwordRequireDigit,
+		PasswordRequireSymbol: passwordRequireSymbol,
+
+		GuestUploadEnabled:  guestUploadEnabled,
+		GuestMaxFileSize:    guestMaxFileSize,
+		GuestUploadsPerHour: guestUploadsPerHour,
+		GuestBytesPerDay:    guestBytesPerDay,
+		GuestImageTTL:       time.Duration(guestImageTTLHours) * time.Hour,
+
+		TrashRetention: time.Duration(trashRetentionDays) * 24 * time.Hour,
+
+		SignedURLSecret: signedURLSecret,
+		SignedURLTTL:    time.Duration(signedURLTTLMinutes) * time.Minute,
+		SignedURLMaxTTL: time.Duration(signedURLMaxTTLHours) * time.Hour,
+
+		ImageCacheMaxAge: time.Duration(imageCacheMaxAgeSeconds) * time.Second,
+
+		ContentNegotiation: contentNegotiation,
+		AVIFEncoder:        avifEncoder,
+
+		HotlinkProtection:  hotlinkProtection,
+		HotlinkAllowed:     hotlinkAllowed,
+		HotlinkDenied:      hotlinkDenied,
+		HotlinkAllowEmpty:  hotlinkAllowEmpty,
+		HotlinkPlaceholder: hotlinkPlaceholder,
+
+		OIDCIssuer:        oidcIssuer,
+		OIDCClientID:      oidcClientID,
+		OIDCClientSecret:  oidcClientSecret,
+		OIDCRedirectURL:   oidcRedirectURL,
+		OIDCScopes:        oidcScopes,
+		OIDCProviderName:  oidcProviderName,
+		OIDCUsernameClaim: oidcUsernameClaim,
+		OIDCRoleClaim:     oidcRoleClaim,
+		OIDCRoleMapping:   oidcRoleMapping,
+		OIDCDefaultRole:   oidcDefaultRole,
+		OIDCAutoProvision: oidcAutoProvision,
+	}
+}
+
+// placeholderSecrets 代码和 .env.example 中自带的示例密钥，任何人都能得到
+var placeholderSecrets = []string{
+	"your-secret-key-change-this-in-production",
+	"your-session-secret-key-change-this-in-production",
+	"your-very-secure-session-secret-key-change-this-in-production",
+}
+
+// IsPlaceholderSecret 判断密钥是否为空或仍是自带的示例值
+func IsPlaceholderSecret(secret string) bool {
+	if strings.TrimSpace(secret) == "" {
+		return true
+	}
+	for _, placeholder := range placeholderSecrets {
+		if secret == placeholder {
+			return true
+		}
 	}
+	return false
+}
+
+// IsTrustedProxy 判断请求的直连地址是否为受信任的反向代理
+func (c *Config) IsTrustedProxy(remoteIP string) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range c.TrustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(proxy); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
 }
 
 func getEnv(key, defaultValue string) string {
@@ -104,3 +436,22 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getList 读取逗号分隔的列表，去除空项并转为小写
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getQuota 读取角色默认配额，如 QUOTA_UPLOADER_BYTES / QUOTA_UPLOADER_IMAGES / QUOTA_UPLOADER_DAILY
+func getQuota(role string) QuotaLimit {
+	bytes, _ := strconv.ParseInt(getEnv("QUOTA_"+role+"_BYTES", "0"), 10, 64)
+	images, _ := strconv.ParseInt(getEnv("QUOTA_"+role+"_IMAGES", "0"), 10, 64)
+	daily, _ := strconv.ParseInt(getEnv("QUOTA_"+role+"_DAILY", "0"), 10, 64)
+	return QuotaLimit{Bytes: bytes, Images: images, Daily: daily}
+}
@@ -5,6 +5,7 @@ import (
 
 	"oneimg/backend/database"
 	"oneimg/backend/models"
+	"oneimg/backend/services"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -14,8 +15,8 @@ import (
 // ChangeAccountInfoRequest 修改登录信息请求结构
 type ChangeAccountInfoRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
-	NewPassword     string `json:"new_password" min=6"`
-	NewUsername     string `json:"new_username" min=3,max=20"`
+	NewPassword     string `json:"new_password" binding:"omitempty,min=6"`
+	NewUsername     string `json:"new_username" binding:"omitempty,min=3,max=20"`
 }
 
 // AccountResponse 账户响应结构
@@ -82,6 +83,21 @@ func ChangeAccountInfo(c *gin.Context) {
 		return
 	}
 
+	if req.NewPassword != "" {
+		username := user.Username
+		if req.NewUsername != "" {
+			username = req.NewUsername
+		}
+		if err := services.PasswordSvc.Validate(req.NewPassword, username); err != nil {
+			c.JSON(http.StatusBadRequest, AccountResponse{
+				Code:    400,
+				Message: err.Error(),
+				Success: false,
+			})
+			return
+		}
+	}
+
 	// 开启事务
 	tx := db.Begin()
 	if err := tx.Error; err != nil {
@@ -96,17 +112,18 @@ func ChangeAccountInfo(c *gin.Context) {
 	// 如果用户名存在修改用户
 	if req.NewUsername != "" {
 		var existingUser models.User
-		if err := db.Where("username = ? AND id != ?", req.NewUsername, userID).First(&existingUser).Error; err == nil {
+		if err := tx.Where("username = ? AND id != ?", req.NewUsername, userID).First(&existingUser).Error; err == nil {
 			c.JSON(http.StatusBadRequest, AccountResponse{
 				Code:    400,
 				Message: "用户名已存在",
 				Success: false,
 			})
+			tx.Rollback()
 			return
 		}
 
 		// 更新用户名
-		if err := db.Model(&user).Update("username", req.NewUsername).Error; err != nil {
+		if err := tx.Model(&user).Update("username", req.NewUsername).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, AccountResponse{
 				Code:    500,
 				Message: "用户名更新失败",
@@ -133,7 +150,7 @@ func ChangeAccountInfo(c *gin.Context) {
 		}
 
 		// 更新密码
-		if err := db.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+		if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, AccountResponse{
 				Code:    500,
 				Message: "密码更新失败",
@@ -143,6 +160,18 @@ func ChangeAccountInfo(c *gin.Context) {
 			tx.Rollback()
 			return
 		}
+
+		// 修改密码后吊销该用户在所有设备上的会话
+		if err := tx.Where("user_id = ?", user.Id).Delete(&models.UserSession{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, AccountResponse{
+				Code:    500,
+				Message: "吊销会话失败",
+				Success: false,
+			})
+			// 回滚事务
+			tx.Rollback()
+			return
+		}
 	}
 
 	// 提交事务
@@ -156,8 +185,9 @@ func ChangeAccountInfo(c *gin.Context) {
 	}
 
 	// 退出登录
+	token, _ := session.Get("session_token").(string)
+	services.SessionSvc.RevokeByToken(token)
 	session.Clear()
-	session.Save()
 
 	if err := session.Save(); err != nil {
 		c.JSON(http.StatusInternalServerError, AccountResponse{
@@ -174,27 +204,3 @@ func ChangeAccountInfo(c *gin.Context) {
 		Success: true,
 	})
 }
-
-// ClearAllSessions 清除所有会话
-func ClearAllSessions(c *gin.Context) {
-	// 获取当前session
-	session := sessions.Default(c)
-
-	// 清除当前session
-	session.Clear()
-	session.Save()
-	if err := session.Save(); err != nil {
-		c.JSON(http.StatusInternalServerError, AccountResponse{
-			Code:    500,
-			Message: "清除会话失败",
-			Success: false,
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, AccountResponse{
-		Code:    200,
-		Message: "所有会话已清除",
-		Success: true,
-	})
-}
@@ -0,0 +1,433 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AlbumItem 相册列表项（含图片数量和封面地址）
+type AlbumItem struct {
+	models.Album
+	ImageCount int64  `json:"image_count"`
+	CoverUrl   string `json:"cover_url"`
+}
+
+// CreateAlbumRequest 创建相册请求结构
+type CreateAlbumRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+// UpdateAlbumRequest 修改相册请求结构，未传的字段保持不变
+// cover_image_id 为0时清除封面（使用第一张图片）
+type UpdateAlbumRequest struct {
+	Name         *string `json:"name" binding:"omitempty,max=100"`
+	Description  *string `json:"description" binding:"omitempty,max=500"`
+	CoverImageId *int    `json:"cover_image_id"`
+}
+
+// AlbumImagesRequest 相册图片操作请求结构
+type AlbumImagesRequest struct {
+	ImageIds []int `json:"image_ids" binding:"required"`
+}
+
+// albumItems 批量构造相册列表项，封面为绝对链接
+// 图片数量和第一张图片的位置用一次分组查询得到，封面图片再用一次查询取回，查询次数与相册数量无关
+func albumItems(requestBase string, albums []models.Album) []AlbumItem {
+	items := make([]AlbumItem, len(albums))
+	if len(albums) == 0 {
+		return items
+	}
+
+	ids := make([]int, len(albums))
+	coverIds := []int{0}
+	coverOf := make(map[int]int, len(albums))
+	for i, album := range albums {
+		items[i].Album = album
+		ids[i] = album.Id
+		if album.CoverImageId != nil {
+			coverIds = append(coverIds, *album.CoverImageId)
+			coverOf[album.Id] = *album.CoverImageId
+		}
+	}
+
+	// 只统计仍存在的图片
+	db := database.GetDB().DB
+	var stats []struct {
+		AlbumId       int
+		ImageCount    int64
+		FirstPosition int
+	}
+	db.Model(&models.Image{}).
+		Select("album_images.album_id, COUNT(*) AS image_count, MIN(album_images.position) AS first_position").
+		Joins("JOIN album_images ON album_images.image_id = images.id").
+		Where("album_images.album_id IN ?", ids).
+		Group("album_images.album_id").
+		Scan(&stats)
+
+	firstPositions := make(map[int]int, len(stats))
+	positions := []int{}
+	for _, stat := range stats {
+		firstPositions[stat.AlbumId] = stat.FirstPosition
+		positions = append(positions, stat.FirstPosition)
+	}
+	if len(positions) == 0 {
+		return items
+	}
+
+	var candidates []struct {
+		AlbumId   int
+		ImageId   int
+		Position  int
+		Url       string
+		PublicUrl string
+	}
+	db.Model(&models.Image{}).
+		Select("album_images.album_id, images.id AS image_id, album_images.position, images.url, images.public_url").
+		Joins("JOIN album_images ON album_images.image_id = images.id").
+		Where("album_images.album_id IN ?", ids).
+		Where(db.Where("images.id IN ?", coverIds).Or("album_images.position IN ?", positions)).
+		Order("images.id ASC").
+		Scan(&candidates)
+
+	// 指定的封面仍在相册中时优先使用，否则使用第一张图片
+	covers := make(map[int]string, len(stats))
+	firsts := make(map[int]string, len(stats))
+	for _, candidate := range candidates {
+		// 优先使用保存的绝对链接，旧版本上传的图片按请求地址生成
+		if candidate.PublicUrl == "" {
+			candidate.PublicUrl = services.LinkSvc.ImageURL(requestBase, &models.Image{Url: candidate.Url})
+		}
+		if coverId, ok := coverOf[candidate.AlbumId]; ok && coverId == candidate.ImageId {
+			covers[candidate.AlbumId] = candidate.PublicUrl
+		}
+		if first, ok := firstPositions[candidate.AlbumId]; ok && first == candidate.Position {
+			if _, exists := firsts[candidate.AlbumId]; !exists {
+				firsts[candidate.AlbumId] = candidate.PublicUrl
+			}
+		}
+	}
+
+	counts := make(map[int]int64, len(stats))
+	for _, stat := range stats {
+		counts[stat.AlbumId] = stat.ImageCount
+	}
+	for i := range items {
+		id := items[i].Id
+		items[i].ImageCount = counts[id]
+		items[i].CoverUrl = covers[id]
+		if items[i].CoverUrl == "" {
+			items[i].CoverUrl = firsts[id]
+		}
+	}
+	return items
+}
+
+// findAlbum 读取路径中的相册，query 决定可见范围
+func findAlbum(c *gin.Context, query *gorm.DB) (*models.Album, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的相册ID",
+		})
+		return nil, false
+	}
+
+	var album models.Album
+	if err := query.First(&album, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "相册不存在",
+		})
+		return nil, false
+	}
+	return &album, true
+}
+
+// GetAlbumList 获取相册列表
+func GetAlbumList(c *gin.Context) {
+	var albums []models.Album
+	if err := readableAlbums(c).Order("updated_at DESC").Find(&albums).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "获取相册列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取相册列表成功",
+		"data": albumItems(requestBaseURL(c), albums),
+	})
+}
+
+// GetAlbumDetail 获取相册详情
+func GetAlbumDetail(c *gin.Context) {
+	album, ok := findAlbum(c, readableAlbums(c))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取相册详情成功",
+		"data": albumItems(requestBaseURL(c), []models.Album{*album})[0],
+	})
+}
+
+// CreateAlbum 创建相册
+func CreateAlbum(c *gin.Context) {
+	var req CreateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "相册名称不能为空",
+		})
+		return
+	}
+
+	userID, _, _ := middlewares.GetCurrentUser(c)
+	album := models.Album{
+		UserId:      userID,
+		Name:        name,
+		Description: req.Description,
+	}
+	if err := database.GetDB().DB.Create(&album).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建相册失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建相册成功",
+		"data": AlbumItem{Album: album},
+	})
+}
+
+// UpdateAlbum 重命名相册、修改描述或设置封面
+func UpdateAlbum(c *gin.Context) {
+	album, ok := findAlbum(c, manageableAlbums(c))
+	if !ok {
+		return
+	}
+
+	var req UpdateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updates := map[string]any{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "相册名称不能为空",
+			})
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.CoverImageId != nil {
+		if *req.CoverImageId == 0 {
+			updates["cover_image_id"] = nil
+		} else {
+			// 封面必须是相册中的图片
+			var count int64
+			readableImages(c).
+				Joins("JOIN album_images ON album_images.image_id = images.id").
+				Where("album_images.album_id = ? AND images.id = ?", album.Id, *req.CoverImageId).
+				Count(&count)
+			if count == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code": 400,
+					"msg":  "封面图片不在该相册中",
+				})
+				return
+			}
+			updates["cover_image_id"] = *req.CoverImageId
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := manageableAlbums(c).Where("id = ?", album.Id).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": 500,
+				"msg":  "更新相册失败",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新相册成功",
+	})
+}
+
+// DeleteAlbum 删除相册，相册中的图片不会被删除
+func DeleteAlbum(c *gin.Context) {
+	album, ok := findAlbum(c, manageableAlbums(c))
+	if !ok {
+		return
+	}
+
+	if err := services.AlbumSvc.Delete(album.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除相册失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除相册成功",
+	})
+}
+
+// AddAlbumImages 向相册添加图片（只能添加自己有权管理的图片）
+func AddAlbumImages(c *gin.Context) {
+	album, ok := findAlbum(c, manageableAlbums(c))
+	if !ok {
+		return
+	}
+
+	var req AlbumImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var imageIDs []int
+	if len(req.ImageIds) > 0 {
+		manageableImages(c).Where("id IN ?", req.ImageIds).Order("id ASC").Pluck("id", &imageIDs)
+	}
+	if len(imageIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "没有可添加的图片",
+		})
+		return
+	}
+
+	// 按请求中的顺序追加
+	allowed := make(map[int]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		allowed[id] = true
+	}
+	ordered := make([]int, 0, len(imageIDs))
+	for _, id := range req.ImageIds {
+		if allowed[id] {
+			ordered = append(ordered, id)
+			delete(allowed, id)
+		}
+	}
+
+	if err := services.AlbumSvc.AddImages(album.Id, ordered); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "添加图片失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "添加图片成功",
+		"data": gin.H{"added": len(ordered)},
+	})
+}
+
+// RemoveAlbumImages 从相册移除图片，图片本身不会被删除
+func RemoveAlbumImages(c *gin.Context) {
+	album, ok := findAlbum(c, manageableAlbums(c))
+	if !ok {
+		return
+	}
+
+	var req AlbumImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := services.AlbumSvc.RemoveImages(album.Id, req.ImageIds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "移除图片失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "移除图片成功",
+	})
+}
+
+// ReorderAlbumImages 调整相册中图片的顺序
+func ReorderAlbumImages(c *gin.Context) {
+	album, ok := findAlbum(c, manageableAlbums(c))
+	if !ok {
+		return
+	}
+
+	var req AlbumImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := services.AlbumSvc.Reorder(album.Id, req.ImageIds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "调整顺序失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "调整顺序成功",
+	})
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaptchaResponse 人机验证参数响应结构
+type CaptchaResponse struct {
+	Code    int                        `json:"code"`
+	Message string                     `json:"message"`
+	Success bool                       `json:"success"`
+	Data    *services.CaptchaChallenge `json:"data,omitempty"`
+}
+
+// GetCaptcha 获取人机验证参数
+func GetCaptcha(c *gin.Context) {
+	challenge, err := services.CaptchaSvc.Challenge()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, CaptchaResponse{
+			Code:    503,
+			Message: "生成验证参数失败: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, CaptchaResponse{
+		Code:    200,
+		Message: "获取验证参数成功",
+		Success: true,
+		Data:    challenge,
+	})
+}
@@ -5,8 +5,26 @@ package controllers
 // - deleteImage.go: DeleteImage
 // - imageList.go: GetImageList
 // - imageDetail.go: GetImageDetail
+// - imageInfo.go: UpdateImageInfo
 // - settings.go: GetSettings, UpdateSettings
 // - userInfo.go: ChangeUserInfo
 // - login.go: Login (已存在)
 // - logout.go: Logout (已存在)
 // - uploadImg.go: UploadImages (已存在)
+// - captcha.go: GetCaptcha
+// - twofactor.go: LoginTwoFactor, SetupTwoFactor, EnableTwoFactor, DisableTwoFactor
+// - sessions.go: ListSessions, RevokeSession, ClearAllSessions
+// - security.go: GetLoginLocks, ClearLoginLock, GetLoginAttempts
+// - users.go: ListUsers, CreateUser, UpdateUserRole, UpdateUserStatus, DeleteUser
+// - quota.go: GetUserQuota, UpdateUserQuota
+// - oidc.go: GetOIDCConfig, OIDCLogin, OIDCCallback
+// - invitations.go: ListInvitations, CreateInvitation, DeleteInvitation, GetInvitation, Register
+// - guest.go: GetGuestConfig, GuestUpload, GetGuestImage, DeleteGuestImage
+// - albums.go: GetAlbumList, GetAlbumDetail, CreateAlbum, UpdateAlbum, DeleteAlbum, AddAlbumImages, RemoveAlbumImages, ReorderAlbumImages
+// - tags.go: GetTagSuggestions, AddImageTags, RemoveImageTags, RenameTag, MergeTags, DeleteTag
+// - trash.go: GetTrashList, RestoreImage, DeleteTrashImage, EmptyTrash
+// - imageBulk.go: BulkImages
+// - serveImage.go: ServeUpload
+// - shares.go: CreateShare, ListShares, RevokeShare, ServeShare, UnlockShare
+// - signedURL.go: CreateSignedURL
+// - imageLinks.go: RewriteImageURLs
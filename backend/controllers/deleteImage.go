@@ -1,19 +1,19 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 
 	"oneimg/backend/config"
 	"oneimg/backend/database"
 	"oneimg/backend/models"
+	"oneimg/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-// DeleteImage 删除图片
+// DeleteImage 删除图片（移入回收站，文件和相册、标签关联保留，可恢复）
 func DeleteImage(c *gin.Context) {
 	// 获取图片ID参数
 	idStr := c.Param("id")
@@ -34,11 +34,10 @@ func DeleteImage(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB().DB
 	var image models.Image
 
-	// 查询图片信息
-	if err := db.First(&image, uint(id)).Error; err != nil {
+	// 查询图片信息（仅限当前用户有权访问的图片）
+	if err := manageableImages(c).First(&image, uint(id)).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code": 404,
 			"msg":  "图片不存在",
@@ -46,36 +45,8 @@ func DeleteImage(c *gin.Context) {
 		return
 	}
 
-	// 获取配置
-	cfg, exists := c.Get("config")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 500,
-			"msg":  "获取配置失败",
-		})
-		return
-	}
-	config := cfg.(*config.Config)
-
-	// 从URL中提取文件路径
-	// URL格式: /uploads/2025/09/filename.ext
-	// 需要去掉前缀 "/uploads/" 得到相对路径
-	relativePath := image.Url
-	if len(relativePath) > 9 && relativePath[:9] == "/uploads/" {
-		relativePath = relativePath[9:] // 去掉 "/uploads/" 前缀
-	}
-
-	// 构建完整文件路径
-	filePath := filepath.Join(config.UploadPath, relativePath)
-
-	// 删除物理文件
-	if err := os.Remove(filePath); err != nil {
-		// 文件可能已经不存在，记录日志但不阻止删除数据库记录
-		// log.Printf("删除文件失败: %v", err)
-	}
-
-	// 删除数据库记录
-	if err := db.Delete(&image).Error; err != nil {
+	// 软删除，彻底删除由回收站完成
+	if err := database.GetDB().DB.Delete(&image).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "删除图片记录失败",
@@ -85,6 +56,13 @@ func DeleteImage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"msg":  "删除图片成功",
+		"msg":  "图片已移入回收站",
 	})
 }
+
+// removeImageFile 删除图片对应的物理文件，失败时只记录日志
+func removeImageFile(cfg *config.Config, image *models.Image) {
+	if err := services.ImageSvc.RemoveFile(cfg.UploadPath, image); err != nil {
+		log.Printf("删除文件失败: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGuestConfig 获取游客上传配置，供上传页展示限制
+func GetGuestConfig(c *gin.Context) {
+	cfg := c.MustGet("config").(*config.Config)
+
+	data := gin.H{"enabled": services.GuestSvc.Enabled()}
+	if services.GuestSvc.Enabled() {
+		data["max_file_size"] = cfg.GuestMaxFileSize
+		data["uploads_per_hour"] = cfg.GuestUploadsPerHour
+		data["bytes_per_day"] = cfg.GuestBytesPerDay
+		data["ttl_hours"] = int(cfg.GuestImageTTL.Hours())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
+// GuestUpload 游客匿名上传单张图片
+// 需要通过人机验证，按IP限流；返回的删除链接只在此时出现一次
+func GuestUpload(c *gin.Context) {
+	if !services.GuestSvc.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "未开放游客上传",
+			"data":    []string{},
+		})
+		return
+	}
+
+	cfg := c.MustGet("config").(*config.Config)
+	clientIP := c.ClientIP()
+
+	// 解析表单前限制请求体大小，超出的部分不会被读入内存或临时文件
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.GuestSvc.MaxFileSize()+guestFormOverhead)
+	if _, err := c.MultipartForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			guestFileTooLarge(c)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "上传失败: " + err.Error(),
+			"data":    []string{},
+		})
+		return
+	}
+
+	if err := services.CaptchaSvc.Verify(c.PostForm("powToken"), clientIP); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "人机验证失败: " + err.Error(),
+			"data":    []string{},
+		})
+		return
+	}
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "上传失败: " + err.Error(),
+			"data":    []string{},
+		})
+		return
+	}
+
+	if header.Size > services.GuestSvc.MaxFileSize() {
+		guestFileTooLarge(c)
+		return
+	}
+
+	if err := ensureUploadDir(cfg.UploadPath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建上传目录失败: " + err.Error(),
+			"data":    []string{},
+		})
+		return
+	}
+
+	// 先预占限额再处理图片，处理失败时释放，避免同一IP并发上传绕过限制
+	reservation, err := services.GuestSvc.Reserve(clientIP, header.Size)
+	if err != nil {
+		if limitErr, ok := err.(*services.GuestLimitError); ok {
+			c.Header("Retry-After", strconv.Itoa(int(limitErr.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    429,
+				"message": limitErr.Message,
+				"data":    []string{},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "检查上传限制失败",
+			"data":    []string{},
+		})
+		return
+	}
+
+	deleteToken, err := services.GuestSvc.NewDeleteToken()
+	if err != nil {
+		services.GuestSvc.Release(reservation)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成删除链接失败",
+			"data":    []string{},
+		})
+		return
+	}
+
+	owner := uploadOwner{
+		GuestIP:     clientIP,
+		ExpiresAt:   services.GuestSvc.ExpiresAt(),
+		DeleteToken: deleteToken,
+	}
+	result := processUploadFile(header, cfg, database.GetDB(), owner, requestBaseURL(c))
+	if !result.Success {
+		services.GuestSvc.Release(reservation)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":       400,
+			"message":    result.Message,
+			"error_code": result.ErrorCode,
+			"data":       []string{},
+		})
+		return
+	}
+
+	result.DeleteURL = siteURL(c, "/guest/delete/"+deleteToken)
+	if owner.ExpiresAt != nil {
+		result.ExpiresAt = owner.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "上传成功",
+		"data":    result,
+	})
+}
+
+// guestFormOverhead 游客上传请求中除图片外的表单字段和multipart边界所允许的大小
+const guestFormOverhead = 64 * 1024
+
+// guestFileTooLarge 返回游客上传文件过大的错误
+func guestFileTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"code":    413,
+		"message": "文件过大，游客最多上传" + strconv.FormatInt(services.GuestSvc.MaxFileSize()/1024/1024, 10) + "MB的图片",
+		"data":    []string{},
+	})
+}
+
+// findGuestImage 根据删除密钥查找游客图片
+func findGuestImage(c *gin.Context) (*models.Image, bool) {
+	token := c.Param("token")
+	var image models.Image
+	if token == "" || database.GetDB().DB.Where("delete_token = ?", token).First(&image).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "图片不存在或已被删除",
+		})
+		return nil, false
+	}
+	return &image, true
+}
+
+// GetGuestImage 根据删除密钥查看图片（删除确认页使用）
+func GetGuestImage(c *gin.Context) {
+	image, ok := findGuestImage(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"url":        image.Url,
+			"public_url": services.LinkSvc.ImageURL(requestBaseURL(c), image),
+			"filename":   image.FileName,
+			"created_at": image.CreatedAt,
+			"expires_at": image.ExpiresAt,
+		},
+	})
+}
+
+// DeleteGuestImage 通过删除链接删除游客图片
+func DeleteGuestImage(c *gin.Context) {
+	image, ok := findGuestImage(c)
+	if !ok {
+		return
+	}
+
+	// 游客没有回收站，直接彻底删除
+	if err := services.TrashSvc.Destroy(image); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除图片失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除图片成功",
+	})
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单次批量操作的图片数量上限
+const maxBulkImages = 1000
+
+// BulkImagesRequest 批量操作请求结构，ids 和 filter 二选一
+// filter 使用图片列表的查询语法（见 imageFilter.go），如 "tags=风景&mime=png&search=海边"
+type BulkImagesRequest struct {
+	Action      string   `json:"action" binding:"required"` // delete, add_to_album, move_to_album, add_tags, remove_tags, set_visibility
+	Ids         []int    `json:"ids"`
+	Filter      string   `json:"filter"`
+	Permanent   bool     `json:"permanent"`     // delete：彻底删除（删除文件），默认移入回收站
+	AlbumId     int      `json:"album_id"`      // add_to_album、move_to_album 的目标相册
+	FromAlbumId int      `json:"from_album_id"` // move_to_album 的来源相册，为0时从自己可管理的其他相册移出
+	Tags        []string `json:"tags"`          // add_tags、remove_tags
+	Private     *bool    `json:"private"`       // set_visibility
+}
+
+// BulkItemResult 涉及文件操作的单张图片处理结果
+type BulkItemResult struct {
+	Id      int    `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkImages 批量删除、加入/移动到相册、添加/移除标签、修改可见性
+// 只处理当前用户有权管理的图片；数据库修改在一个事务中完成，彻底删除时逐张返回文件删除结果
+func BulkImages(c *gin.Context) {
+	var req BulkImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 先校验操作参数，再查找图片
+	var tags []string
+	switch req.Action {
+	case "delete":
+	case "add_to_album", "move_to_album":
+		albumIDs := []int{req.AlbumId}
+		if req.Action == "move_to_album" && req.FromAlbumId > 0 {
+			if req.FromAlbumId == req.AlbumId {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code": 400,
+					"msg":  "来源相册和目标相册不能相同",
+				})
+				return
+			}
+			albumIDs = append(albumIDs, req.FromAlbumId)
+		}
+		for _, albumID := range albumIDs {
+			var count int64
+			manageableAlbums(c).Where("id = ?", albumID).Count(&count)
+			if count == 0 {
+				c.JSON(http.StatusNotFound, gin.H{
+					"code": 404,
+					"msg":  fmt.Sprintf("相册 %d 不存在", albumID),
+				})
+				return
+			}
+		}
+	case "add_tags", "remove_tags":
+		if tags = services.NormalizeTags(req.Tags); len(tags) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "标签不能为空且长度不能超过64个字符",
+			})
+			return
+		}
+	case "set_visibility":
+		if req.Private == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "缺少参数 private",
+			})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "不支持的批量操作: " + req.Action,
+		})
+		return
+	}
+
+	imageIDs, missing, err := bulkTargets(c, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{
+		"action":  req.Action,
+		"matched": len(imageIDs),
+	}
+	if req.Filter == "" {
+		data["missing"] = missing
+	}
+	if len(imageIDs) == 0 {
+		data["affected"] = 0
+		c.JSON(http.StatusOK, gin.H{
+			"code": 200,
+			"msg":  "没有符合条件的图片",
+			"data": data,
+		})
+		return
+	}
+
+	db := database.GetDB().DB
+	switch req.Action {
+	case "delete":
+		if req.Permanent {
+			bulkDestroy(c, imageIDs, data)
+			return
+		}
+		err = db.Where("id IN ?", imageIDs).Delete(&models.Image{}).Error
+	case "add_to_album":
+		err = services.AlbumSvc.AddImages(req.AlbumId, imageIDs)
+	case "move_to_album":
+		// 未指定来源相册时只从当前用户可管理的其他相册中移出
+		from := manageableAlbums(c)
+		if req.FromAlbumId > 0 {
+			from = from.Where("id = ?", req.FromAlbumId)
+		}
+		err = services.AlbumSvc.MoveImages(from, req.AlbumId, imageIDs)
+	case "add_tags":
+		err = services.TagSvc.AddToImages(imageIDs, tags)
+		var limitErr *services.TagLimitError
+		if errors.As(err, &limitErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  limitErr.Error(),
+				"data": gin.H{"ids": limitErr.ImageIDs},
+			})
+			return
+		}
+	case "remove_tags":
+		err = services.TagSvc.RemoveFromImages(imageIDs, tags)
+	case "set_visibility":
+		err = db.Model(&models.Image{}).Where("id IN ?", imageIDs).Update("private", *req.Private).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "批量操作失败，所有修改已回滚",
+		})
+		return
+	}
+
+	data["affected"] = len(imageIDs)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "批量操作成功",
+		"data": data,
+	})
+}
+
+// bulkTargets 查找批量操作涉及的图片ID，按ID指定时同时返回不存在或无权管理的ID
+func bulkTargets(c *gin.Context, req *BulkImagesRequest) ([]int, []int, error) {
+	if (len(req.Ids) > 0) == (strings.TrimSpace(req.Filter) != "") {
+		return nil, nil, fmt.Errorf("参数 ids 和 filter 必须且只能传一个")
+	}
+
+	var imageIDs []int
+	if len(req.Ids) > 0 {
+		if len(req.Ids) > maxBulkImages {
+			return nil, nil, fmt.Errorf("单次最多操作%d张图片", maxBulkImages)
+		}
+		if err := manageableImages(c).Where("images.id IN ?", req.Ids).
+			Order("images.id ASC").Pluck("images.id", &imageIDs).Error; err != nil {
+			return nil, nil, fmt.Errorf("查询图片失败")
+		}
+
+		found := make(map[int]bool, len(imageIDs))
+		for _, id := range imageIDs {
+			found[id] = true
+		}
+		missing := []int{}
+		for _, id := range req.Ids {
+			if !found[id] {
+				missing = append(missing, id)
+				found[id] = true
+			}
+		}
+		return imageIDs, missing, nil
+	}
+
+	params, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(req.Filter), "?"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("参数 filter 格式错误")
+	}
+	filter, err := parseImageFilter(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := filter.apply(manageableImages(c))
+	if search := params.Get("search"); search != "" {
+		query, _ = services.SearchSvc.Apply(query, search)
+	}
+	if filter.AlbumID > 0 {
+		query = query.Where("images.id IN (?)", database.GetDB().DB.Model(&models.AlbumImage{}).
+			Select("image_id").Where("album_id = ?", filter.AlbumID))
+	}
+
+	// 多取一条判断是否超过上限
+	if err := query.Order("images.id ASC").Limit(maxBulkImages+1).Pluck("images.id", &imageIDs).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询图片失败")
+	}
+	if len(imageIDs) > maxBulkImages {
+		return nil, nil, fmt.Errorf("符合条件的图片超过%d张，请缩小筛选范围后分批操作", maxBulkImages)
+	}
+	return imageIDs, nil, nil
+}
+
+// bulkDestroy 逐张彻底删除图片，单张失败不影响其他图片
+func bulkDestroy(c *gin.Context, imageIDs []int, data gin.H) {
+	var images []models.Image
+	database.GetDB().DB.Where("id IN ?", imageIDs).Order("id ASC").Find(&images)
+
+	results := make([]BulkItemResult, 0, len(images))
+	destroyed := 0
+	for i := range images {
+		result := BulkItemResult{Id: images[i].Id, Success: true}
+		if err := services.TrashSvc.Destroy(&images[i]); err != nil {
+			result.Success, result.Error = false, err.Error()
+		} else {
+			destroyed++
+		}
+		results = append(results, result)
+	}
+
+	data["affected"] = destroyed
+	data["results"] = results
+	msg := "批量删除成功"
+	if destroyed < len(images) {
+		msg = fmt.Sprintf("%d张图片删除失败", len(images)-destroyed)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+		"data": data,
+	})
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 游标分页（GET /api/images?cursor=）：
+// 传入 cursor 参数（首页为空字符串）即使用游标分页，响应中的 next_cursor 用于获取下一页，为空表示没有更多数据。
+// 游标记录排序字段的值和图片ID，新上传的图片不会导致翻页时重复或遗漏；
+// 游标模式默认不返回总数，需要时传 with_total=true。不支持按相关度排序，搜索时默认按上传时间排列。
+
+var errInvalidCursor = errors.New("无效的分页游标")
+
+// imageCursor 游标内容，编码为 base64url(JSON) 后对客户端不透明
+type imageCursor struct {
+	SortBy string          `json:"s"`
+	Order  string          `json:"o"`
+	Value  json.RawMessage `json:"v"`
+	Id     int             `json:"i"`
+}
+
+// 游标分页可用的排序字段，position 为相册内顺序
+var cursorSortFields = map[string]string{
+	"created_at": "images.created_at",
+	"filename":   "images.original_name",
+	"file_size":  "images.file_size",
+	"width":      "images.width",
+	"height":     "images.height",
+	"resolution": "images.width * images.height",
+	"position":   "album_images.position",
+}
+
+// encodeCursor 根据当前页最后一张图片生成下一页的游标
+func encodeCursor(sortBy, order string, image *models.Image, albumID int) string {
+	var value any
+	switch sortBy {
+	case "created_at":
+		value = image.CreatedAt
+	case "filename":
+		value = image.OriginalName
+	case "file_size":
+		value = image.FileSize
+	case "width":
+		value = image.Width
+	case "height":
+		value = image.Height
+	case "resolution":
+		value = image.Width * image.Height
+	case "position":
+		var position int
+		database.GetDB().DB.Model(&models.AlbumImage{}).
+			Where("album_id = ? AND image_id = ?", albumID, image.Id).
+			Select("position").Scan(&position)
+		value = position
+	}
+
+	raw, _ := json.Marshal(value)
+	data, _ := json.Marshal(imageCursor{SortBy: sortBy, Order: order, Value: raw, Id: image.Id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标，排序方式必须与本次请求一致
+func decodeCursor(token, sortBy, order string) (*imageCursor, any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, nil, errInvalidCursor
+	}
+	var cursor imageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id < 1 {
+		return nil, nil, errInvalidCursor
+	}
+	if cursor.SortBy != sortBy || cursor.Order != order {
+		return nil, nil, errors.New("分页游标与排序方式不一致，请从第一页重新获取")
+	}
+
+	var value any
+	switch sortBy {
+	case "created_at":
+		var t time.Time
+		err = json.Unmarshal(cursor.Value, &t)
+		value = database.QueryTime(t)
+	case "filename":
+		var s string
+		err = json.Unmarshal(cursor.Value, &s)
+		value = s
+	default:
+		var n int64
+		err = json.Unmarshal(cursor.Value, &n)
+		value = n
+	}
+	if err != nil {
+		return nil, nil, errInvalidCursor
+	}
+	return &cursor, value, nil
+}
+
+// listImagesByCursor 游标分页获取图片列表，query 已包含全部筛选条件
+func listImagesByCursor(c *gin.Context, query *gorm.DB, filter *ImageFilter, sortBy, sortOrder string, limit int) {
+	// 确定排序字段：相册中默认按相册内顺序，其余默认按上传时间
+	if c.Query("sort_by") == "" {
+		sortBy = "created_at"
+		if filter.AlbumID > 0 {
+			sortBy, sortOrder = "position", "asc"
+		}
+	}
+	field, ok := cursorSortFields[sortBy]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "游标分页不支持该排序字段: " + sortBy,
+		})
+		return
+	}
+
+	data := gin.H{"limit": limit}
+	if c.Query("with_total") == "true" {
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": 500,
+				"msg":  "获取图片总数失败",
+			})
+			return
+		}
+		data["total"] = total
+	}
+
+	// 排序值相同时按图片ID排序，保证顺序稳定
+	op, direction := "<", "DESC"
+	if sortOrder == "asc" {
+		op, direction = ">", "ASC"
+	}
+	if token := c.Query("cursor"); token != "" {
+		cursor, value, err := decodeCursor(token, sortBy, sortOrder)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		query = query.Where("("+field+" "+op+" ? OR ("+field+" = ? AND images.id "+op+" ?))", value, value, cursor.Id)
+	}
+
+	// 多取一条判断是否还有下一页
+	var images []models.Image
+	err := query.Select("images.*").
+		Order(field + " " + direction + ", images.id " + direction).
+		Limit(limit + 1).
+		Find(&images).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "获取图片列表失败",
+		})
+		return
+	}
+
+	nextCursor := ""
+	if len(images) > limit {
+		images = images[:limit]
+		nextCursor = encodeCursor(sortBy, sortOrder, &images[limit-1], filter.AlbumID)
+	}
+
+	services.TagSvc.Attach(images)
+	services.LinkSvc.Fill(requestBaseURL(c), images)
+	if search := c.Query("search"); search != "" {
+		services.SearchSvc.Highlight(images, search)
+	}
+
+	data["images"] = images
+	data["next_cursor"] = nextCursor
+	data["has_more"] = nextCursor != ""
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取图片列表成功",
+		"data": data,
+	})
+}
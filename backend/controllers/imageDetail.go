@@ -4,8 +4,8 @@ import (
 	"net/http"
 	"strconv"
 
-	"oneimg/backend/database"
 	"oneimg/backend/models"
+	"oneimg/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,11 +31,10 @@ func GetImageDetail(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB().DB
 	var image models.Image
 
-	// 查询图片详情
-	if err := db.First(&image, uint(id)).Error; err != nil {
+	// 查询图片详情（仅限当前用户有权访问的图片）
+	if err := readableImages(c).First(&image, uint(id)).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code": 404,
 			"msg":  "图片不存在",
@@ -43,6 +42,11 @@ func GetImageDetail(c *gin.Context) {
 		return
 	}
 
+	images := []models.Image{image}
+	services.TagSvc.Attach(images)
+	services.LinkSvc.Fill(requestBaseURL(c), images)
+	image = images[0]
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "获取图片详情成功",
@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"gorm.io/gorm"
+)
+
+// 图片列表查询语法（GET /api/images，批量操作的 filter 使用相同语法），所有条件同时生效，格式错误时返回400：
+//
+//	search=关键字                         全文检索，见 SearchSvc.Apply
+//	tags=风景,旅行  tag_mode=any|all        包含任一/全部标签，默认 any
+//	album_id=1                            相册中的图片
+//	date_from=2024-01-01  date_to=...     上传时间范围，日期（date_to 包含当天）或 RFC3339 时间
+//	mime=webp,image/gif                   MIME类型，逗号分隔，可省略 image/ 前缀
+//	min_width / max_width                 宽度范围（像素，包含边界）
+//	min_height / max_height               高度范围（像素，包含边界）
+//	orientation=portrait|landscape|square 竖图 / 横图 / 方图
+//	visibility=public|private             公开 / 私有图片
+//	min_size / max_size                   文件大小范围，字节数或带单位（如 500KB、2MB）
+//	sort_by=created_at|filename|file_size|width|height|resolution|relevance
+//	sort_order=asc|desc                   默认 desc
+
+// ImageFilter 图片列表筛选条件
+type ImageFilter struct {
+	Tags        []string
+	TagMode     string
+	AlbumID     int
+	DateFrom    *time.Time
+	DateTo      *time.Time // 不包含
+	MimeTypes   []string
+	MinWidth    *int
+	MaxWidth    *int
+	MinHeight   *int
+	MaxHeight   *int
+	Orientation string
+	Visibility  string
+	MinSize     *int64
+	MaxSize     *int64
+}
+
+// 排序字段与数据库表达式的对应关系
+var imageSortFields = map[string]string{
+	"created_at": "images.created_at",
+	"filename":   "images.original_name",
+	"file_size":  "images.file_size",
+	"width":      "images.width",
+	"height":     "images.height",
+	"resolution": "images.width * images.height",
+	"relevance":  "", // 由 SearchSvc.Apply 提供
+}
+
+// parseImageFilter 解析并校验筛选参数
+func parseImageFilter(params url.Values) (*ImageFilter, error) {
+	f := &ImageFilter{
+		Tags:    services.ParseTags(params.Get("tags")),
+		TagMode: params.Get("tag_mode"),
+	}
+	if f.TagMode == "" {
+		f.TagMode = "any"
+	}
+	if f.TagMode != "any" && f.TagMode != "all" {
+		return nil, fmt.Errorf("参数 tag_mode 只能是 any 或 all")
+	}
+
+	var err error
+	if f.AlbumID, err = parseIntParam(params, "album_id"); err != nil {
+		return nil, err
+	}
+	if f.DateFrom, err = parseTimeParam(params, "date_from", false); err != nil {
+		return nil, err
+	}
+	if f.DateTo, err = parseTimeParam(params, "date_to", true); err != nil {
+		return nil, err
+	}
+	if f.DateFrom != nil && f.DateTo != nil && !f.DateFrom.Before(*f.DateTo) {
+		return nil, fmt.Errorf("参数 date_from 不能晚于 date_to")
+	}
+
+	for _, mime := range strings.Split(params.Get("mime"), ",") {
+		mime = strings.ToLower(strings.TrimSpace(mime))
+		if mime == "" {
+			continue
+		}
+		if !strings.Contains(mime, "/") {
+			mime = "image/" + mime
+		}
+		f.MimeTypes = append(f.MimeTypes, mime)
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **int
+	}{
+		{"min_width", &f.MinWidth},
+		{"max_width", &f.MaxWidth},
+		{"min_height", &f.MinHeight},
+		{"max_height", &f.MaxHeight},
+	} {
+		if params.Get(param.name) == "" {
+			continue
+		}
+		value, err := parseIntParam(params, param.name)
+		if err != nil {
+			return nil, err
+		}
+		*param.target = &value
+	}
+	if err := checkRange("width", f.MinWidth, f.MaxWidth); err != nil {
+		return nil, err
+	}
+	if err := checkRange("height", f.MinHeight, f.MaxHeight); err != nil {
+		return nil, err
+	}
+
+	f.Orientation = params.Get("orientation")
+	if f.Orientation != "" && f.Orientation != "portrait" && f.Orientation != "landscape" && f.Orientation != "square" {
+		return nil, fmt.Errorf("参数 orientation 只能是 portrait、landscape 或 square")
+	}
+	f.Visibility = params.Get("visibility")
+	if f.Visibility != "" && f.Visibility != "public" && f.Visibility != "private" {
+		return nil, fmt.Errorf("参数 visibility 只能是 public 或 private")
+	}
+
+	if f.MinSize, err = parseSizeParam(params, "min_size"); err != nil {
+		return nil, err
+	}
+	if f.MaxSize, err = parseSizeParam(params, "max_size"); err != nil {
+		return nil, err
+	}
+	if f.MinSize != nil && f.MaxSize != nil && *f.MinSize > *f.MaxSize {
+		return nil, fmt.Errorf("参数 min_size 不能大于 max_size")
+	}
+	return f, nil
+}
+
+// apply 把筛选条件加到图片查询上（相册筛选需要连接 album_images，由调用方处理）
+func (f *ImageFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.Tags) > 0 {
+		tagged := database.GetDB().DB.Model(&models.ImageTag{}).
+			Select("image_tags.image_id").
+			Joins("JOIN tags ON tags.id = image_tags.tag_id").
+			Where("tags.name IN ?", f.Tags)
+		if f.TagMode == "all" {
+			tagged = tagged.Group("image_tags.image_id").
+				Having("COUNT(DISTINCT image_tags.tag_id) = ?", len(f.Tags))
+		}
+		query = query.Where("images.id IN (?)", tagged)
+	}
+	if f.DateFrom != nil {
+		query = query.Where("images.created_at >= ?", *f.DateFrom)
+	}
+	if f.DateTo != nil {
+		query = query.Where("images.created_at < ?", *f.DateTo)
+	}
+	if len(f.MimeTypes) > 0 {
+		query = query.Where("images.mime_type IN ?", f.MimeTypes)
+	}
+	if f.MinWidth != nil {
+		query = query.Where("images.width >= ?", *f.MinWidth)
+	}
+	if f.MaxWidth != nil {
+		query = query.Where("images.width <= ?", *f.MaxWidth)
+	}
+	if f.MinHeight != nil {
+		query = query.Where("images.height >= ?", *f.MinHeight)
+	}
+	if f.MaxHeight != nil {
+		query = query.Where("images.height <= ?", *f.MaxHeight)
+	}
+	switch f.Orientation {
+	case "portrait":
+		query = query.Where("images.height > images.width")
+	case "landscape":
+		query = query.Where("images.width > images.height")
+	case "square":
+		query = query.Where("images.width = images.height")
+	}
+	if f.Visibility != "" {
+		query = query.Where("images.private = ?", f.Visibility == "private")
+	}
+	if f.MinSize != nil {
+		query = query.Where("images.file_size >= ?", *f.MinSize)
+	}
+	if f.MaxSize != nil {
+		query = query.Where("images.file_size <= ?", *f.MaxSize)
+	}
+	return query
+}
+
+// parseIntParam 解析非负整数参数，未传时返回0
+func parseIntParam(params url.Values, name string) (int, error) {
+	value := params.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("参数 %s 必须是非负整数", name)
+	}
+	return n, nil
+}
+
+// parseTimeParam 解析日期（YYYY-MM-DD，存储时区）或 RFC3339 时间
+// endOfDay 为 true 时，日期表示当天结束（返回次日零点，作为不包含的上界）
+func parseTimeParam(params url.Values, name string, endOfDay bool) (*time.Time, error) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, database.Location); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("参数 %s 必须是 YYYY-MM-DD 格式的日期或 RFC3339 时间", name)
+	}
+	if endOfDay {
+		// 精确时间作为包含的上界
+		t = t.Add(time.Nanosecond)
+	}
+	t = database.QueryTime(t)
+	return &t, nil
+}
+
+// parseSizeParam 解析文件大小，支持字节数或 KB、MB、GB 单位（1024进制，不区分大小写）
+func parseSizeParam(params url.Values, name string) (*int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(params.Get(name)))
+	if value == "" {
+		return nil, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(n >= 0) || math.IsInf(n, 1) {
+		return nil, fmt.Errorf("参数 %s 必须是字节数或带单位的大小（如 500KB、2MB）", name)
+	}
+	size := int64(n * float64(multiplier))
+	return &size, nil
+}
+
+// checkRange 校验最小值不大于最大值
+func checkRange(name string, lower, upper *int) error {
+	if lower != nil && upper != nil && *lower > *upper {
+		return fmt.Errorf("参数 min_%s 不能大于 max_%s", name, name)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateImageInfoRequest 修改图片信息请求结构，未传的字段保持不变
+type UpdateImageInfoRequest struct {
+	Title        *string `json:"title" binding:"omitempty,max=200"`
+	Description  *string `json:"description" binding:"omitempty,max=2000"`
+	AltText      *string `json:"alt_text" binding:"omitempty,max=500"`
+	OriginalName *string `json:"original_name" binding:"omitempty,max=255"`
+	Private      *bool   `json:"private"`
+}
+
+// UpdateImageInfo 修改图片标题、描述、替代文本、原始文件名和可见性
+func UpdateImageInfo(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的图片ID",
+		})
+		return
+	}
+
+	var image models.Image
+	if err := manageableImages(c).First(&image, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "图片不存在",
+		})
+		return
+	}
+
+	var req UpdateImageInfoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updates := map[string]any{}
+	if req.Title != nil {
+		updates["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.AltText != nil {
+		updates["alt_text"] = strings.TrimSpace(*req.AltText)
+	}
+	if req.OriginalName != nil {
+		updates["original_name"] = originalFileName(*req.OriginalName)
+	}
+	if req.Private != nil {
+		updates["private"] = *req.Private
+	}
+
+	if len(updates) > 0 {
+		err := database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Image{}).Where("id = ?", image.Id).Updates(updates).Error; err != nil {
+				return err
+			}
+			return services.SearchSvc.Index(tx, []int{image.Id})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": 500,
+				"msg":  "更新图片信息失败",
+			})
+			return
+		}
+		manageableImages(c).First(&image, image.Id)
+	}
+
+	images := []models.Image{image}
+	services.TagSvc.Attach(images)
+	services.LinkSvc.Fill(requestBaseURL(c), images)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新图片信息成功",
+		"data": images[0],
+	})
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RewriteImageURLsRequest 重写图片链接请求结构，请求体或 base_url 为空时使用当前配置的图片地址
+type RewriteImageURLsRequest struct {
+	BaseURL string `json:"base_url"`
+}
+
+// RewriteImageURLs 更换CDN或图片域名后，按新地址重写所有图片保存的绝对链接
+func RewriteImageURLs(c *gin.Context) {
+	var req RewriteImageURLsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"success": false,
+		})
+		return
+	}
+
+	base := strings.TrimRight(strings.TrimSpace(req.BaseURL), "/")
+	if base == "" {
+		base = services.LinkSvc.ImageBase()
+	}
+	if base == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "未配置图片地址，请传入 base_url 或设置 IMAGE_BASE_URL",
+			"success": false,
+		})
+		return
+	}
+	if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "base_url 必须是 http 或 https 地址",
+			"success": false,
+		})
+		return
+	}
+
+	updated, err := services.LinkSvc.Rewrite(base)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "重写图片链接失败: " + err.Error(),
+			"success": false,
+			"data":    gin.H{"updated": updated},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "图片链接已更新",
+		"success": true,
+		"data":    gin.H{"base_url": base, "updated": updated},
+	})
+}
@@ -4,13 +4,13 @@ import (
 	"net/http"
 	"strconv"
 
-	"oneimg/backend/database"
 	"oneimg/backend/models"
+	"oneimg/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetImageList 获取图片列表
+// GetImageList 获取图片列表，查询参数语法见 imageFilter.go
 func GetImageList(c *gin.Context) {
 	// 获取分页参数
 	pageStr := c.DefaultQuery("page", "1")
@@ -18,17 +18,49 @@ func GetImageList(c *gin.Context) {
 
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
-		page = 1
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数 page 必须是正整数",
+		})
+		return
 	}
 
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit < 1 || limit > 100 {
-		limit = 20
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数 limit 必须是 1-100 之间的整数",
+		})
+		return
 	}
 
 	// 获取排序参数
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	sortOrder := c.DefaultQuery("sort_order", "desc")
+	if _, ok := imageSortFields[sortBy]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "不支持的排序字段: " + sortBy,
+		})
+		return
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数 sort_order 只能是 asc 或 desc",
+		})
+		return
+	}
+
+	// 获取筛选参数
+	filter, err := parseImageFilter(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
 
 	// 获取搜索参数
 	search := c.Query("search")
@@ -36,58 +68,62 @@ func GetImageList(c *gin.Context) {
 	// 计算偏移量
 	offset := (page - 1) * limit
 
-	db := database.GetDB().DB
-
 	var images []models.Image
 	var total int64
 
-	// 构建查询
-	query := db.Model(&models.Image{})
+	// 构建查询（当前用户可浏览的图片）
+	query := filter.apply(readableImages(c))
 
-	// 添加搜索条件
+	// 添加搜索条件（文件名、标题、描述、替代文本、标签和EXIF），relevance 为按相关度排序的表达式
+	var relevance any
 	if search != "" {
-		query = query.Where("file_name LIKE ?", "%"+search+"%")
+		query, relevance = services.SearchSvc.Apply(query, search)
 	}
 
-	// 获取总数
-	if err := query.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 500,
-			"msg":  "获取图片总数失败",
-		})
-		return
+	// 按相册筛选
+	if filter.AlbumID > 0 {
+		var count int64
+		readableAlbums(c).Where("id = ?", filter.AlbumID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code": 404,
+				"msg":  "相册不存在",
+			})
+			return
+		}
+		query = query.Joins("JOIN album_images ON album_images.image_id = images.id AND album_images.album_id = ?", filter.AlbumID)
 	}
 
-	// 验证排序字段
-	validSortFields := map[string]bool{
-		"created_at": true,
-		"file_size":  true,
-		"filename":   true,
+	// 游标分页
+	if _, ok := c.GetQuery("cursor"); ok {
+		listImagesByCursor(c, query, filter, sortBy, sortOrder, limit)
+		return
 	}
 
-	if !validSortFields[sortBy] {
-		sortBy = "created_at"
+	// 获取总数（with_total=false 时跳过，适合只需要翻页的场景）
+	withTotal := c.DefaultQuery("with_total", "true") != "false"
+	if withTotal {
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": 500,
+				"msg":  "获取图片总数失败",
+			})
+			return
+		}
 	}
 
-	if sortOrder != "asc" && sortOrder != "desc" {
-		sortOrder = "desc"
+	// 获取图片列表，搜索时默认按相关度排列，相册中默认按相册内顺序排列
+	// 全文检索不可用时 relevance 为nil，按上传时间排列
+	var orderClause any = "images.created_at " + sortOrder + ", images.id DESC"
+	if field := imageSortFields[sortBy]; field != "" {
+		orderClause = field + " " + sortOrder + ", images.id DESC"
 	}
-
-	// 映射前端字段名到数据库字段名
-	fieldMapping := map[string]string{
-		"filename":   "file_name",
-		"created_at": "created_at",
-		"file_size":  "file_size",
+	if relevance != nil && (sortBy == "relevance" || c.Query("sort_by") == "") {
+		orderClause = relevance
+	} else if filter.AlbumID > 0 && c.Query("sort_by") == "" {
+		orderClause = "album_images.position ASC"
 	}
-
-	dbField := fieldMapping[sortBy]
-	if dbField == "" {
-		dbField = "created_at"
-	}
-
-	// 获取图片列表
-	orderClause := dbField + " " + sortOrder
-	if err := query.Order(orderClause).Offset(offset).Limit(limit).Find(&images).Error; err != nil {
+	if err := query.Select("images.*").Order(orderClause).Offset(offset).Limit(limit).Find(&images).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "获取图片列表失败",
@@ -95,18 +131,26 @@ func GetImageList(c *gin.Context) {
 		return
 	}
 
-	// 计算总页数
-	totalPages := (total + int64(limit) - 1) / int64(limit)
+	services.TagSvc.Attach(images)
+	services.LinkSvc.Fill(requestBaseURL(c), images)
+	if search != "" {
+		services.SearchSvc.Highlight(images, search)
+	}
+
+	data := gin.H{
+		"images": images,
+		"page":   page,
+		"limit":  limit,
+	}
+	if withTotal {
+		// 计算总页数
+		data["total"] = total
+		data["total_pages"] = (total + int64(limit) - 1) / int64(limit)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "获取图片列表成功",
-		"data": gin.H{
-			"images":      images,
-			"total":       total,
-			"page":        page,
-			"limit":       limit,
-			"total_pages": totalPages,
-		},
+		"data": data,
 	})
 }
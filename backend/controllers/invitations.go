@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// InvitationItem 邀请码列表项（含注册链接）
+type InvitationItem struct {
+	models.Invitation
+	Link   string `json:"link"`
+	Usable bool   `json:"usable"`
+}
+
+// CreateInvitationRequest 生成邀请码请求结构
+type CreateInvitationRequest struct {
+	Role           string `json:"role" binding:"omitempty,oneof=admin uploader viewer"`
+	MaxUses        int    `json:"max_uses" binding:"min=0"`         // 0表示不限次数
+	ExpiresInHours int    `json:"expires_in_hours" binding:"min=0"` // 0表示永不过期
+	Note           string `json:"note" binding:"max=255"`
+}
+
+// RegisterRequest 邀请注册请求结构
+type RegisterRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Username string `json:"username" binding:"required,min=3,max=20"`
+	Password string `json:"password" binding:"required"`
+	PowToken string `json:"powToken"` // 人机验证token，关闭验证时可为空
+}
+
+// newInvitationItem 构造邀请码列表项
+func newInvitationItem(c *gin.Context, invitation models.Invitation) InvitationItem {
+	return InvitationItem{
+		Invitation: invitation,
+		Link:       siteURL(c, "/register?code="+invitation.Code),
+		Usable:     invitation.IsUsable(time.Now()),
+	}
+}
+
+// ListInvitations 获取所有邀请码
+func ListInvitations(c *gin.Context) {
+	var invitations []models.Invitation
+	if err := database.GetDB().DB.Order("id DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "获取邀请码列表失败",
+			Success: false,
+		})
+		return
+	}
+
+	list := make([]InvitationItem, 0, len(invitations))
+	for _, invitation := range invitations {
+		list = append(list, newInvitationItem(c, invitation))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取邀请码列表成功",
+		"success": true,
+		"data":    list,
+	})
+}
+
+// CreateInvitation 生成邀请码
+func CreateInvitation(c *gin.Context) {
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoleUploader
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	userID, _, _ := middlewares.GetCurrentUser(c)
+	invitation, err := services.InvitationSvc.Create(userID, role, req.MaxUses, expiresAt, req.Note)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "生成邀请码失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "邀请码已生成",
+		"success": true,
+		"data":    newInvitationItem(c, *invitation),
+	})
+}
+
+// DeleteInvitation 删除（作废）邀请码，已注册的用户不受影响
+func DeleteInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "无效的邀请码ID",
+			Success: false,
+		})
+		return
+	}
+
+	result := database.GetDB().DB.Delete(&models.Invitation{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "删除邀请码失败",
+			Success: false,
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, AccountResponse{
+			Code:    404,
+			Message: "邀请码不存在",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AccountResponse{
+		Code:    200,
+		Message: "邀请码已删除",
+		Success: true,
+	})
+}
+
+// GetInvitation 查询邀请码是否有效（注册页使用）
+func GetInvitation(c *gin.Context) {
+	invitation, err := services.InvitationSvc.Find(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AccountResponse{
+			Code:    404,
+			Message: "邀请码无效或已过期",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "邀请码有效",
+		"success": true,
+		"data": gin.H{
+			"role":       invitation.Role,
+			"expires_at": invitation.ExpiresAt,
+		},
+	})
+}
+
+// Register 使用邀请码注册新用户，注册成功后自动登录
+func Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	if err := services.CaptchaSvc.Verify(req.PowToken, clientIP); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Code:    400,
+			Message: "人机验证失败: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	if err := services.PasswordSvc.Validate(req.Password, req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Code:    400,
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Code:    500,
+			Message: "密码加密失败",
+			Success: false,
+		})
+		return
+	}
+
+	user, err := services.InvitationSvc.Redeem(req.Code, req.Username, string(hashedPassword))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvitationInvalid):
+			c.JSON(http.StatusBadRequest, LoginResponse{
+				Code:    400,
+				Message: "邀请码无效或已过期",
+				Success: false,
+			})
+		case errors.Is(err, services.ErrUsernameTaken):
+			c.JSON(http.StatusBadRequest, LoginResponse{
+				Code:    400,
+				Message: "用户名已存在",
+				Success: false,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, LoginResponse{
+				Code:    500,
+				Message: "注册失败",
+				Success: false,
+			})
+		}
+		return
+	}
+
+	if message, err := establishSession(c, user, clientIP, userAgent); err != nil {
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Code:    500,
+			Message: message,
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Code:    200,
+		Message: "注册成功",
+		Success: true,
+		User: &User{
+			ID:       user.Id,
+			Username: user.Username,
+		},
+	})
+}
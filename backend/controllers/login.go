@@ -1,84 +1,39 @@
 package controllers
 
 import (
-	"encoding/json"
-	"io"
-	"log"
+	"fmt"
+	"math"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
+	"oneimg/backend/config"
 	"oneimg/backend/database"
 	"oneimg/backend/models"
+	"oneimg/backend/services"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func ValidatePowToken(token string) bool {
-	if token == "" {
-		return false
-	}
-
-	// 构建请求体
-	type reqBody struct {
-		Token string `json:"token"`
-	}
-	body, err := json.Marshal(reqBody{Token: token})
-	if err != nil {
-		return false
-	}
-
-	// 创建请求
-	req, err := http.NewRequest("POST", "https://cha.eta.im/api/validate", strings.NewReader(string(body)))
-	if err != nil {
-		return false
-	}
-
-	// 关键修复：使用与前端完全一致的浏览器头信息
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "application/json, text/plain, */*")
-	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
-
-	// 发送请求
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		Transport: &http.Transport{
-			DisableCompression: true,
-		},
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
-
-	// 解析响应
-	respBody, _ := io.ReadAll(resp.Body)
-
-	var validationResp struct {
-		Success bool `json:"success"`
-	}
-	json.Unmarshal(respBody, &validationResp)
-	return validationResp.Success
-}
-
 // LoginRequest 登录请求结构
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	PowToken string `json:"powToken"` // 去掉omitempty，确保始终能接收到该字段
+	PowToken string `json:"powToken"` // 人机验证token，关闭验证时可为空
 }
 
 // LoginResponse 登录响应结构
 type LoginResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Success bool   `json:"success"`
-	Token   string `json:"token,omitempty"`
-	User    *User  `json:"user,omitempty"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	Success    bool   `json:"success"`
+	Token      string `json:"token,omitempty"`
+	User       *User  `json:"user,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"` // 被限制登录时需要等待的秒数
+
+	TwoFactorRequired bool `json:"two_factor_required,omitempty"` // 需要继续提交两步验证码
 }
 
 // User 用户信息结构（不包含密码）
@@ -99,25 +54,28 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 日志：输出接收到的POW token（可在调试完成后移除）
-	log.Printf("接收到的POW token: %s", req.PowToken)
-
-	// 验证POW token - 修复条件判断逻辑
-	// 如果服务要求必须提供POW token，则使用下面的判断
-	if req.PowToken == "" {
-		c.JSON(http.StatusBadRequest, LoginResponse{
-			Code:    400,
-			Message: "请提供POW token",
-			Success: false,
+	clientIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	// 检查是否处于失败退避或锁定期
+	if wait := services.LoginGuardSvc.Check(clientIP, req.Username); wait > 0 {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, LoginResponse{
+			Code:       429,
+			Message:    fmt.Sprintf("登录失败次数过多，请在 %d 秒后重试", retryAfter),
+			Success:    false,
+			RetryAfter: retryAfter,
 		})
 		return
 	}
 
-	// 验证POW token
-	if !ValidatePowToken(req.PowToken) {
+	// 人机验证（由配置的验证提供者决定，关闭时直接通过）
+	if err := services.CaptchaSvc.Verify(req.PowToken, clientIP); err != nil {
+		services.LoginGuardSvc.RecordIPFailure(clientIP, req.Username, userAgent, "人机验证失败")
 		c.JSON(http.StatusBadRequest, LoginResponse{
 			Code:    400,
-			Message: "POW验证失败",
+			Message: "人机验证失败: " + err.Error(),
 			Success: false,
 		})
 		return
@@ -138,6 +96,7 @@ func Login(c *gin.Context) {
 	var user models.User
 	result := db.DB.Where("username = ?", req.Username).First(&user)
 	if result.Error != nil {
+		services.LoginGuardSvc.RecordFailure(clientIP, req.Username, userAgent, "用户不存在")
 		c.JSON(http.StatusUnauthorized, LoginResponse{
 			Code:    401,
 			Message: "用户名或密码错误",
@@ -148,6 +107,7 @@ func Login(c *gin.Context) {
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		services.LoginGuardSvc.RecordFailure(clientIP, req.Username, userAgent, "密码错误")
 		c.JSON(http.StatusUnauthorized, LoginResponse{
 			Code:    401,
 			Message: "用户名或密码错误",
@@ -156,28 +116,49 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 获取session
-	session := sessions.Default(c)
+	// 已禁用的账号不允许登录
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, LoginResponse{
+			Code:    403,
+			Message: "账号已被禁用",
+			Success: false,
+		})
+		return
+	}
 
-	// 设置session数据
-	session.Set("user_id", user.Id)
-	session.Set("username", user.Username)
-	session.Set("logged_in", true)
+	// 开启两步验证的用户需要先提交验证码，此时会话尚未标记为已登录
+	if user.TotpEnabled {
+		session := sessions.Default(c)
+		session.Clear()
+		session.Set("pending_user_id", user.Id)
+		session.Set("pending_at", time.Now().Unix())
+		if err := session.Save(); err != nil {
+			c.JSON(http.StatusInternalServerError, LoginResponse{
+				Code:    500,
+				Message: "保存会话失败",
+				Success: false,
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, LoginResponse{
+			Code:              202,
+			Message:           "请输入两步验证码",
+			Success:           false,
+			TwoFactorRequired: true,
+		})
+		return
+	}
 
-	// 设置session选项
-	session.Options(sessions.Options{
-		MaxAge:   24 * 60 * 60,            // 24小时，单位秒
-		HttpOnly: true,                    // 防止XSS攻击
-		Secure:   false,                   // 生产环境应设为true（需要HTTPS）
-		SameSite: http.SameSiteStrictMode, // 防止CSRF攻击
-		Path:     "/",                     // cookie路径
-	})
+	completeLogin(c, &user, clientIP, userAgent)
+}
 
-	// 保存session
-	if err := session.Save(); err != nil {
+// completeLogin 写入登录会话并返回登录成功响应
+func completeLogin(c *gin.Context, user *models.User, clientIP, userAgent string) {
+	if message, err := establishSession(c, user, clientIP, userAgent); err != nil {
 		c.JSON(http.StatusInternalServerError, LoginResponse{
 			Code:    500,
-			Message: "保存会话失败",
+			Message: message,
 			Success: false,
 		})
 		return
@@ -194,3 +175,42 @@ func Login(c *gin.Context) {
 		},
 	})
 }
+
+// establishSession 登记服务端会话并写入session，失败时返回提示信息
+func establishSession(c *gin.Context, user *models.User, clientIP, userAgent string) (string, error) {
+	cfg := c.MustGet("config").(*config.Config)
+
+	// 在服务端登记会话，用于会话管理和吊销
+	record, err := services.SessionSvc.Create(user.Id, clientIP, userAgent)
+	if err != nil {
+		return "创建会话失败", err
+	}
+
+	// 获取session
+	session := sessions.Default(c)
+
+	// 设置session数据
+	session.Delete("pending_user_id")
+	session.Delete("pending_at")
+	session.Set("user_id", user.Id)
+	session.Set("username", user.Username)
+	session.Set("session_token", record.Token)
+	session.Set("logged_in", true)
+
+	// 设置session选项
+	session.Options(sessions.Options{
+		MaxAge:   int(cfg.SessionMaxAge.Seconds()), // 默认24小时，单位秒
+		HttpOnly: true,                             // 防止XSS攻击
+		Secure:   false,                            // 生产环境应设为true（需要HTTPS）
+		SameSite: http.SameSiteStrictMode,          // 防止CSRF攻击
+		Path:     "/",                              // cookie路径
+	})
+
+	// 保存session
+	if err := session.Save(); err != nil {
+		return "保存会话失败", err
+	}
+
+	services.LoginGuardSvc.RecordSuccess(clientIP, user.Username, userAgent)
+	return "", nil
+}
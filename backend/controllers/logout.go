@@ -3,6 +3,8 @@ package controllers
 import (
 	"net/http"
 
+	"oneimg/backend/services"
+
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +18,11 @@ type LogoutResponse struct {
 // Logout 用户退出登录
 func Logout(c *gin.Context) {
 	session := sessions.Default(c)
+
+	// 同时删除服务端会话记录
+	token, _ := session.Get("session_token").(string)
+	services.SessionSvc.RevokeByToken(token)
+
 	session.Clear()
 	if err := session.Save(); err != nil {
 		c.JSON(http.StatusInternalServerError, LogoutResponse{
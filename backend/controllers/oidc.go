@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 保存签名后的登录流程（state、nonce和PKCE verifier）的cookie，需要在身份提供方跳转回来时携带，因此使用 SameSite=Lax
+const oidcStateCookie = "oneimg-oidc-state"
+
+// GetOIDCConfig 获取单点登录配置，供登录页决定是否显示SSO按钮
+func GetOIDCConfig(c *gin.Context) {
+	data := gin.H{"enabled": services.OIDCSvc.Enabled()}
+	if services.OIDCSvc.Enabled() {
+		data["name"] = services.OIDCSvc.Name()
+		data["login_url"] = "/api/oidc/login"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
+// OIDCLogin 跳转到身份提供方登录
+func OIDCLogin(c *gin.Context) {
+	if !services.OIDCSvc.Enabled() {
+		c.JSON(http.StatusNotFound, LoginResponse{
+			Code:    404,
+			Message: "未启用单点登录",
+			Success: false,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	authURL, flow, err := services.OIDCSvc.AuthURL(ctx, requestBaseURL(c)+"/api/oidc/callback")
+	if err != nil {
+		log.Printf("单点登录跳转失败: %v", err)
+		redirectLoginError(c, "无法连接身份提供方")
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    flow,
+		Path:     "/api/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 身份提供方回调，校验后登录或自动创建用户
+// 单点登录不再要求本地两步验证，多因素认证由身份提供方负责
+func OIDCCallback(c *gin.Context) {
+	if !services.OIDCSvc.Enabled() {
+		c.JSON(http.StatusNotFound, LoginResponse{
+			Code:    404,
+			Message: "未启用单点登录",
+			Success: false,
+		})
+		return
+	}
+
+	// 无论成功与否，state只使用一次
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if errCode := c.Query("error"); errCode != "" {
+		message := c.Query("error_description")
+		if message == "" {
+			message = errCode
+		}
+		redirectLoginError(c, "身份提供方拒绝登录: "+message)
+		return
+	}
+
+	// state必须与发起登录的浏览器一致，防止登录CSRF
+	flow, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		redirectLoginError(c, "登录请求已失效，请重新登录")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	clientIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	identity, err := services.OIDCSvc.Exchange(ctx, flow, c.Query("state"), c.Query("code"))
+	if err != nil {
+		log.Printf("单点登录校验失败: %v", err)
+		if errors.Is(err, services.ErrOIDCInvalidState) {
+			redirectLoginError(c, "登录请求已失效，请重新登录")
+		} else {
+			redirectLoginError(c, "身份校验失败")
+		}
+		return
+	}
+
+	user, err := services.OIDCSvc.ResolveUser(identity)
+	if err != nil {
+		if errors.Is(err, services.ErrOIDCNotProvisioned) {
+			services.LoginGuardSvc.RecordFailure(clientIP, identity.Username, userAgent, "sso_not_provisioned")
+			redirectLoginError(c, "该账号未开通，请联系管理员")
+			return
+		}
+		log.Printf("单点登录创建用户失败: %v", err)
+		redirectLoginError(c, "登录失败")
+		return
+	}
+
+	if user.Disabled {
+		services.LoginGuardSvc.RecordFailure(clientIP, user.Username, userAgent, "disabled")
+		redirectLoginError(c, "账号已被禁用")
+		return
+	}
+
+	if message, err := establishSession(c, user, clientIP, userAgent); err != nil {
+		redirectLoginError(c, message)
+		return
+	}
+	c.Redirect(http.StatusFound, "/")
+}
+
+// redirectLoginError 跳转回登录页并显示错误信息
+func redirectLoginError(c *gin.Context, message string) {
+	c.Redirect(http.StatusFound, "/login?sso_error="+url.QueryEscape(message))
+}
+
+// requestBaseURL 根据请求推断站点地址
+// 只有来自受信任反向代理的请求才采用 X-Forwarded-Proto
+func requestBaseURL(c *gin.Context) string {
+	cfg := c.MustGet("config").(*config.Config)
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); (proto == "http" || proto == "https") && cfg.IsTrustedProxy(c.RemoteIP()) {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// siteURL 站点页面的绝对链接，配置了 PUBLIC_BASE_URL 时使用配置的地址
+func siteURL(c *gin.Context, path string) string {
+	return services.LinkSvc.SiteURL(requestBaseURL(c), path)
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// readableImages 返回当前用户可浏览的图片查询，可安全地重复用于多次查询
+// 拥有 image:view_all 权限（管理员、访客）时为全部图片，否则仅为本人的图片
+func readableImages(c *gin.Context) *gorm.DB {
+	return scopedImages(c, middlewares.PermImageViewAll)
+}
+
+// manageableImages 返回当前用户可修改、删除的图片查询
+// 拥有 image:manage_all 权限（管理员）时为全部图片，否则仅为本人的图片
+func manageableImages(c *gin.Context) *gorm.DB {
+	return scopedImages(c, middlewares.PermImageManageAll)
+}
+
+// readableAlbums 返回当前用户可浏览的相册查询，范围规则与图片相同
+func readableAlbums(c *gin.Context) *gorm.DB {
+	return scopedOwned(c, &models.Album{}, middlewares.PermImageViewAll)
+}
+
+// manageableAlbums 返回当前用户可修改、删除的相册查询
+func manageableAlbums(c *gin.Context) *gorm.DB {
+	return scopedOwned(c, &models.Album{}, middlewares.PermImageManageAll)
+}
+
+// scopedImages 按权限限定图片查询范围
+func scopedImages(c *gin.Context, allPermission string) *gorm.DB {
+	return scopedOwned(c, &models.Image{}, allPermission)
+}
+
+// scopedOwned 按权限限定带 user_id 字段的模型的查询范围
+func scopedOwned(c *gin.Context, model any, allPermission string) *gorm.DB {
+	query := database.GetDB().DB.Model(model)
+	if !middlewares.HasPermission(c, allPermission) {
+		userID, _, _ := middlewares.GetCurrentUser(c)
+		query = query.Where("user_id = ?", userID)
+	}
+	return query.Session(&gorm.Session{})
+}
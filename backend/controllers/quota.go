@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaInfo 配额信息
+type QuotaInfo struct {
+	Limits services.QuotaLimits `json:"limits"`
+	Usage  services.QuotaUsage  `json:"usage"`
+}
+
+// UpdateUserQuotaRequest 修改用户配额请求结构
+// 字段为 null 时恢复为角色默认配额，0表示不限制
+type UpdateUserQuotaRequest struct {
+	QuotaBytes  *int64 `json:"quota_bytes" binding:"omitempty,min=0"`
+	QuotaImages *int64 `json:"quota_images" binding:"omitempty,min=0"`
+	QuotaDaily  *int64 `json:"quota_daily" binding:"omitempty,min=0"`
+}
+
+// GetUserQuota 获取当前用户的配额和用量
+func GetUserQuota(c *gin.Context) {
+	user, ok := currentUserRecord(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, AccountResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return
+	}
+
+	usage, err := services.QuotaSvc.Usage(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "获取配额用量失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取配额成功",
+		"success": true,
+		"data": QuotaInfo{
+			Limits: services.QuotaSvc.Limits(user),
+			Usage:  usage,
+		},
+	})
+}
+
+// UpdateUserQuota 修改指定用户的个人配额
+func UpdateUserQuota(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "无效的用户ID",
+			Success: false,
+		})
+		return
+	}
+
+	var req UpdateUserQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	db := database.GetDB().DB
+
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, AccountResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return
+	}
+
+	// 使用map更新，使 null 能写入数据库
+	err = db.Model(&user).Updates(map[string]any{
+		"quota_bytes":  req.QuotaBytes,
+		"quota_images": req.QuotaImages,
+		"quota_daily":  req.QuotaDaily,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "更新用户配额失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AccountResponse{
+		Code:    200,
+		Message: "用户配额已更新",
+		Success: true,
+	})
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityResponse 安全管理响应结构
+type SecurityResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// GetLoginLocks 获取当前处于锁定或退避期的IP和用户名
+func GetLoginLocks(c *gin.Context) {
+	locks, err := services.LoginGuardSvc.ListLocks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, SecurityResponse{
+			Code:    500,
+			Message: "获取锁定列表失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SecurityResponse{
+		Code:    200,
+		Message: "获取锁定列表成功",
+		Success: true,
+		Data:    locks,
+	})
+}
+
+// ClearLoginLock 解除指定的锁定
+func ClearLoginLock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, SecurityResponse{
+			Code:    400,
+			Message: "无效的锁定ID",
+			Success: false,
+		})
+		return
+	}
+
+	found, err := services.LoginGuardSvc.ClearLock(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, SecurityResponse{
+			Code:    500,
+			Message: "解除锁定失败",
+			Success: false,
+		})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, SecurityResponse{
+			Code:    404,
+			Message: "锁定记录不存在",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SecurityResponse{
+		Code:    200,
+		Message: "解除锁定成功",
+		Success: true,
+	})
+}
+
+// ClearAllLoginLocks 解除全部锁定
+func ClearAllLoginLocks(c *gin.Context) {
+	if err := services.LoginGuardSvc.ClearAllLocks(); err != nil {
+		c.JSON(http.StatusInternalServerError, SecurityResponse{
+			Code:    500,
+			Message: "解除锁定失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SecurityResponse{
+		Code:    200,
+		Message: "已解除全部锁定",
+		Success: true,
+	})
+}
+
+// GetLoginAttempts 分页获取登录审计记录
+func GetLoginAttempts(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.GetDB().DB.Model(&models.LoginAttempt{})
+
+	// 可选过滤条件
+	if username := c.Query("username"); username != "" {
+		query = query.Where("username = ?", username)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if success := c.Query("success"); success != "" {
+		query = query.Where("success = ?", success == "true")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, SecurityResponse{
+			Code:    500,
+			Message: "获取登录记录总数失败",
+			Success: false,
+		})
+		return
+	}
+
+	var attempts []models.LoginAttempt
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, SecurityResponse{
+			Code:    500,
+			Message: "获取登录记录失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SecurityResponse{
+		Code:    200,
+		Message: "获取登录记录成功",
+		Success: true,
+		Data: gin.H{
+			"attempts": attempts,
+			"total":    total,
+			"page":     page,
+			"limit":    limit,
+		},
+	})
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeUpload 提供 /uploads 下图片文件的访问（需在 OptionalAuthMiddleware、SignedURLMiddleware 之后使用）
+// 只提供有图片记录的文件；回收站中的图片不可访问
+// 私有图片只对有权浏览的登录用户或带有效签名的请求可见，其他人通过分享链接访问
+func ServeUpload(c *gin.Context) {
+	url := "/uploads" + path.Clean("/"+c.Param("filepath"))
+
+	var image models.Image
+	if err := database.GetDB().DB.Where("url = ?", url).First(&image).Error; err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	// 私有图片对无权访问的用户表现为不存在
+	if image.Private && !c.GetBool("signed_url") && !canViewPrivate(c, &image) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	serveImageFile(c, &image)
+}
+
+// canViewPrivate 判断当前登录用户能否直接访问私有图片
+func canViewPrivate(c *gin.Context, image *models.Image) bool {
+	userID, _, ok := middlewares.GetCurrentUser(c)
+	if !ok || !middlewares.HasPermission(c, middlewares.PermImageView) {
+		return false
+	}
+	return image.UserId == userID || middlewares.HasPermission(c, middlewares.PermImageViewAll)
+}
+
+// serveImageFile 输出图片文件
+// 图片上传后内容不再变化，ETag 为内容哈希（强校验）；公开图片可能改为私有，
+// 只缓存 IMAGE_CACHE_MAX_AGE_SECONDS，到期后通过 ETag 重新验证，改为私有后最迟在该时长后生效
+// 条件请求（If-None-Match、If-Modified-Since）和 Range 请求由 http.ServeContent 处理
+// 开启格式协商时，WebP 图片按 Accept 请求头或 format 参数输出备用格式，URL保持不变
+func serveImageFile(c *gin.Context, image *models.Image) {
+	serveImageFileWith(c, image, nil)
+}
+
+// serveImageFileWith 同 serveImageFile，文件打开成功后、输出内容前调用 opened，
+// opened 返回 false 时不再输出（由 opened 自行写入响应）
+func serveImageFileWith(c *gin.Context, image *models.Image, opened func() bool) {
+	cfg := c.MustGet("config").(*config.Config)
+	filePath, mimeType, variant := services.ImageSvc.FilePath(cfg.UploadPath, image), image.MimeType, ""
+	if cfg.ContentNegotiation && image.MimeType == "image/webp" {
+		c.Writer.Header().Add("Vary", "Accept")
+		// 缺少备用格式时（上传前已有的图片、生成失败或队列已满）加入后台生成队列
+		services.AlternateSvc.Enqueue(image)
+		if format := requestedFormat(c, cfg, image); format != services.FormatOriginal && format != services.FormatWebP {
+			if alt, err := services.ImageSvc.Alternate(cfg.UploadPath, image, format); err == nil {
+				filePath, mimeType, variant = alt.Path, alt.MimeType, alt.Variant
+			} else {
+				// 备用格式生成前输出原文件且不缓存，避免缓存住不符合协商结果的内容
+				c.Header("Cache-Control", "no-store")
+			}
+		}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if opened != nil && !opened() {
+		return
+	}
+
+	// 不同格式的内容不同，ETag 需要区分
+	if hash, err := services.ImageSvc.EnsureContentHash(cfg.UploadPath, image); err == nil {
+		if variant != "" {
+			hash += "-" + variant
+		}
+		c.Header("ETag", `"`+hash+`"`)
+	} else {
+		log.Printf("计算图片 %d 的内容哈希失败: %v", image.Id, err)
+	}
+	if image.Private {
+		c.Header("Cache-Control", "private, no-store")
+	} else if c.Writer.Header().Get("Cache-Control") == "" {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, must-revalidate", int(cfg.ImageCacheMaxAge.Seconds())))
+	}
+	c.Header("Content-Type", mimeType)
+	http.ServeContent(c.Writer, c.Request, image.FileName, info.ModTime(), file)
+}
+
+// requestedFormat 确定输出格式：format 参数（original、webp、avif、jpeg）优先，否则按 Accept 请求头协商
+func requestedFormat(c *gin.Context, cfg *config.Config, image *models.Image) string {
+	switch c.Query("format") {
+	case "original", "webp":
+		return services.FormatOriginal
+	case "avif":
+		return services.FormatAVIF
+	case "jpeg", "jpg":
+		return services.FormatFallback
+	}
+	return services.NegotiateFormat(c.GetHeader("Accept"), services.ImageSvc.HasAVIF(cfg.UploadPath, image))
+}
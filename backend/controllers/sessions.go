@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionInfo 会话列表项
+type SessionInfo struct {
+	models.UserSession
+	Current bool `json:"current"`
+}
+
+// ListSessions 获取当前用户的所有登录会话
+func ListSessions(c *gin.Context) {
+	userID, _, _ := middlewares.GetCurrentUser(c)
+	currentID := c.GetInt("session_id")
+
+	records, err := services.SessionSvc.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "获取会话列表失败",
+			Success: false,
+		})
+		return
+	}
+
+	list := make([]SessionInfo, 0, len(records))
+	for _, record := range records {
+		list = append(list, SessionInfo{
+			UserSession: record,
+			Current:     record.Id == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取会话列表成功",
+		"success": true,
+		"data":    list,
+	})
+}
+
+// RevokeSession 吊销指定会话（吊销当前会话等同于退出登录）
+func RevokeSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "无效的会话ID",
+			Success: false,
+		})
+		return
+	}
+
+	userID, _, _ := middlewares.GetCurrentUser(c)
+	found, err := services.SessionSvc.Revoke(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "吊销会话失败",
+			Success: false,
+		})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, AccountResponse{
+			Code:    404,
+			Message: "会话不存在",
+			Success: false,
+		})
+		return
+	}
+
+	if id == c.GetInt("session_id") {
+		session := sessions.Default(c)
+		session.Clear()
+		session.Save()
+	}
+
+	c.JSON(http.StatusOK, AccountResponse{
+		Code:    200,
+		Message: "会话已吊销",
+		Success: true,
+	})
+}
+
+// ClearAllSessions 吊销当前用户在所有设备上的会话
+// 传入 keep_current=true 时保留当前会话，只让其他设备下线
+func ClearAllSessions(c *gin.Context) {
+	userID, _, _ := middlewares.GetCurrentUser(c)
+	keepCurrent := c.Query("keep_current") == "true"
+
+	exceptID := 0
+	if keepCurrent {
+		exceptID = c.GetInt("session_id")
+	}
+
+	if _, err := services.SessionSvc.RevokeAll(userID, exceptID); err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "清除会话失败",
+			Success: false,
+		})
+		return
+	}
+
+	if !keepCurrent {
+		// 清除当前session
+		session := sessions.Default(c)
+		session.Clear()
+		if err := session.Save(); err != nil {
+			c.JSON(http.StatusInternalServerError, AccountResponse{
+				Code:    500,
+				Message: "清除会话失败",
+				Success: false,
+			})
+			return
+		}
+	}
+
+	message := "所有会话已清除"
+	if keepCurrent {
+		message = "其他设备的会话已清除"
+	}
+	c.JSON(http.StatusOK, AccountResponse{
+		Code:    200,
+		Message: message,
+		Success: true,
+	})
+}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 保存分享密码验证凭据的cookie，按分享路径隔离
+const shareProofCookie = "oneimg-share"
+
+// ShareItem 分享链接列表项
+type ShareItem struct {
+	models.ShareLink
+	Link        string `json:"link"`
+	HasPassword bool   `json:"has_password"`
+	Usable      bool   `json:"usable"`
+}
+
+// CreateShareRequest 生成分享链接请求结构，expires_at 优先于 expires_in_hours
+type CreateShareRequest struct {
+	ExpiresAt      *time.Time `json:"expires_at"`
+	ExpiresInHours int        `json:"expires_in_hours" binding:"min=0"` // 0表示永不过期
+	MaxViews       int        `json:"max_views" binding:"min=0"`        // 0表示不限次数
+	Password       string     `json:"password" binding:"max=72"`
+}
+
+// sharePage 分享落地页（输入密码或提示链接不可用）
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body{margin:0;min-height:100vh;display:flex;align-items:center;justify-content:center;font-family:system-ui,sans-serif;background:#f5f5f5;color:#333}
+.box{background:#fff;padding:32px;border-radius:8px;box-shadow:0 2px 12px rgba(0,0,0,.08);width:300px;text-align:center}
+input{box-sizing:border-box;width:100%;padding:10px;margin:16px 0 12px;border:1px solid #ddd;border-radius:4px}
+button{width:100%;padding:10px;border:0;border-radius:4px;background:#3b82f6;color:#fff;cursor:pointer}
+.error{color:#dc2626;font-size:14px}
+</style>
+</head>
+<body>
+<div class="box">
+<h3>{{.Title}}</h3>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Form}}<form method="post">
+<input type="password" name="password" placeholder="请输入访问密码" autofocus required>
+<button type="submit">查看图片</button>
+</form>{{end}}
+</div>
+</body>
+</html>`))
+
+// renderSharePage 输出分享落地页
+func renderSharePage(c *gin.Context, status int, title, errMsg string, form bool) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(status)
+	sharePage.Execute(c.Writer, gin.H{"Title": title, "Error": errMsg, "Form": form})
+}
+
+// newShareItem 构造分享链接列表项
+func newShareItem(c *gin.Context, link models.ShareLink) ShareItem {
+	return ShareItem{
+		ShareLink:   link,
+		Link:        siteURL(c, "/s/"+link.Token),
+		HasPassword: link.PasswordHash != "",
+		Usable:      link.IsUsable(time.Now()),
+	}
+}
+
+// manageableShares 当前用户有权管理的分享链接（即可管理图片的分享链接）
+func manageableShares(c *gin.Context) *gorm.DB {
+	return database.GetDB().DB.Model(&models.ShareLink{}).
+		Where("image_id IN (?)", manageableImages(c).Select("images.id"))
+}
+
+// CreateShare 为图片生成分享链接
+func CreateShare(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的图片ID",
+		})
+		return
+	}
+
+	var image models.Image
+	if err := manageableImages(c).First(&image, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "图片不存在",
+		})
+		return
+	}
+
+	var req CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil && req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "过期时间必须晚于当前时间",
+		})
+		return
+	}
+
+	userID, _, _ := middlewares.GetCurrentUser(c)
+	link, err := services.ShareSvc.Create(image.Id, userID, expiresAt, req.MaxViews, req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "生成分享链接失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "分享链接已生成",
+		"data": newShareItem(c, *link),
+	})
+}
+
+// ListShares 获取分享链接列表，可按 image_id 筛选
+func ListShares(c *gin.Context) {
+	query := manageableShares(c)
+	if imageID := c.Query("image_id"); imageID != "" {
+		id, err := strconv.Atoi(imageID)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "无效的图片ID",
+			})
+			return
+		}
+		query = query.Where("image_id = ?", id)
+	}
+
+	var links []models.ShareLink
+	if err := query.Order("id DESC").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "获取分享链接失败",
+		})
+		return
+	}
+
+	list := make([]ShareItem, 0, len(links))
+	for _, link := range links {
+		list = append(list, newShareItem(c, link))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取分享链接成功",
+		"data": list,
+	})
+}
+
+// RevokeShare 撤销分享链接
+func RevokeShare(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的分享链接ID",
+		})
+		return
+	}
+
+	result := manageableShares(c).Where("id = ?", id).Delete(&models.ShareLink{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "撤销分享链接失败",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "分享链接不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "分享链接已撤销",
+	})
+}
+
+// findShare 查找分享链接，不可用时输出提示页
+func findShare(c *gin.Context) (*models.ShareLink, *models.Image, bool) {
+	link, image, err := services.ShareSvc.Find(c.Param("token"))
+	if errors.Is(err, services.ErrShareExpired) {
+		renderSharePage(c, http.StatusGone, "分享链接已失效", "链接已过期或访问次数已用完", false)
+		return nil, nil, false
+	}
+	if err != nil {
+		renderSharePage(c, http.StatusNotFound, "分享链接不存在", "链接不存在或已被撤销", false)
+		return nil, nil, false
+	}
+	return link, image, true
+}
+
+// ServeShare 通过分享链接访问图片，有密码且未验证时显示密码输入页
+// 每次完整输出图片（GET 且返回200）计为一次访问，HEAD 请求不计数
+func ServeShare(c *gin.Context) {
+	link, image, ok := findShare(c)
+	if !ok {
+		return
+	}
+
+	if link.PasswordHash != "" {
+		proof, _ := c.Cookie(shareProofCookie)
+		if !services.ShareSvc.VerifyProof(link, proof) {
+			renderSharePage(c, http.StatusOK, "查看分享的图片", "", true)
+			return
+		}
+	}
+
+	// 每次访问都要计数，不使用缓存和分段下载，避免通过304或Range请求重复读取却不计数
+	for _, header := range []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since"} {
+		c.Request.Header.Del(header)
+	}
+	c.Header("Cache-Control", "private, no-store")
+	// 文件打开成功后才计数，文件缺失时不消耗访问次数
+	serveImageFileWith(c, image, func() bool {
+		if c.Request.Method != http.MethodGet {
+			return true
+		}
+		if err := services.ShareSvc.RecordView(link); err != nil {
+			renderSharePage(c, http.StatusGone, "分享链接已失效", "链接已过期或访问次数已用完", false)
+			return false
+		}
+		return true
+	})
+}
+
+// UnlockShare 校验分享密码，通过后写入凭据cookie并跳转回图片
+// 按IP以及IP+分享链接限制密码尝试次数，与登录共用防爆破服务；
+// 不按分享链接单独计数，避免任何人输错几次密码就让所有访问者无法解锁
+func UnlockShare(c *gin.Context) {
+	link, _, ok := findShare(c)
+	if !ok {
+		return
+	}
+	if link.PasswordHash == "" {
+		c.Redirect(http.StatusSeeOther, c.Request.URL.Path)
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+	guardKey := "share:" + link.Token + ":" + clientIP
+	if wait := services.LoginGuardSvc.Check(clientIP, guardKey); wait > 0 {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		renderSharePage(c, http.StatusTooManyRequests, "查看分享的图片", fmt.Sprintf("密码错误次数过多，请在 %d 秒后重试", retryAfter), true)
+		return
+	}
+	if !services.ShareSvc.CheckPassword(link, c.PostForm("password")) {
+		services.LoginGuardSvc.RecordFailure(clientIP, guardKey, userAgent, "分享密码错误")
+		renderSharePage(c, http.StatusUnauthorized, "查看分享的图片", "密码错误", true)
+		return
+	}
+	services.LoginGuardSvc.RecordSuccess(clientIP, guardKey, userAgent)
+
+	maxAge := 24 * 3600
+	if link.ExpiresAt != nil {
+		maxAge = min(maxAge, int(time.Until(*link.ExpiresAt).Seconds())+1)
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(shareProofCookie, services.ShareSvc.Proof(link), maxAge, c.Request.URL.Path, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusSeeOther, c.Request.URL.Path)
+}
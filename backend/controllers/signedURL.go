@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSignedURLRequest 生成签名URL请求结构
+type CreateSignedURLRequest struct {
+	ExpiresIn int               `json:"expires_in" binding:"min=0"` // 有效期（秒），0表示使用默认有效期
+	Params    map[string]string `json:"params"`                     // 附加的查询参数（如图片变换参数），一并签名
+}
+
+// CreateSignedURL 为图片生成带签名和有效期的URL，私有图片可凭此URL免登录访问
+// 签名URL无需登录且在有效期内一直有效，只有图片所有者或可管理全部图片的用户可以生成
+func CreateSignedURL(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的图片ID",
+		})
+		return
+	}
+
+	var image models.Image
+	if err := manageableImages(c).First(&image, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "图片不存在",
+		})
+		return
+	}
+
+	var req CreateSignedURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	params := url.Values{}
+	for key, value := range req.Params {
+		if key == services.SignedURLExpires || key == services.SignedURLSignature {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "参数名不能是 " + key,
+			})
+			return
+		}
+		params.Set(key, value)
+	}
+
+	query, expiresAt, err := services.SignedURLSvc.Sign(image.Url, params, time.Duration(req.ExpiresIn)*time.Second)
+	if errors.Is(err, services.ErrSignedURLOff) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code": 503,
+			"msg":  "未配置签名密钥 SIGNED_URL_SECRET，无法生成签名URL",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  fmt.Sprintf("有效期不能超过%d秒", int(services.SignedURLSvc.MaxTTL().Seconds())),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "签名URL已生成",
+		"data": gin.H{
+			"url":        services.LinkSvc.ImageURL(requestBaseURL(c), &image) + "?" + query.Encode(),
+			"expires_at": expiresAt,
+		},
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"net/http"
 	"time"
 
-	"oneimg/backend/database"
 	"oneimg/backend/models"
 
 	"github.com/gin-gonic/gin"
@@ -52,7 +51,8 @@ type SizeDistributionItem struct {
 
 // GetDashboardStats 获取仪表板统计数据
 func GetDashboardStats(c *gin.Context) {
-	db := database.GetDB().DB
+	// 统计范围限定为当前用户可浏览的图片
+	db := readableImages(c)
 
 	var stats DashboardStats
 
@@ -178,7 +178,8 @@ func getSizeDistribution(db *gorm.DB) []SizeDistributionItem {
 
 // GetImageStats 获取图片详细统计
 func GetImageStats(c *gin.Context) {
-	db := database.GetDB().DB
+	// 统计范围限定为当前用户可浏览的图片
+	db := readableImages(c)
 
 	// 获取查询参数
 	period := c.DefaultQuery("period", "month") // day, week, month, year
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImageTagsRequest 图片标签操作请求结构
+type ImageTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// RenameTagRequest 重命名标签请求结构
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MergeTagsRequest 合并标签请求结构
+type MergeTagsRequest struct {
+	SourceIds []int `json:"source_ids" binding:"required"`
+	TargetId  int   `json:"target_id" binding:"required"`
+}
+
+// findTag 读取路径中的标签
+func findTag(c *gin.Context) (*models.Tag, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的标签ID",
+		})
+		return nil, false
+	}
+
+	var tag models.Tag
+	if err := database.GetDB().DB.First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "标签不存在",
+		})
+		return nil, false
+	}
+	return &tag, true
+}
+
+// bindImageTags 读取路径中可管理的图片和请求中的标签
+func bindImageTags(c *gin.Context) (*models.Image, []string, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的图片ID",
+		})
+		return nil, nil, false
+	}
+
+	var image models.Image
+	if err := manageableImages(c).First(&image, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "图片不存在",
+		})
+		return nil, nil, false
+	}
+
+	var req ImageTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return nil, nil, false
+	}
+
+	tags := services.NormalizeTags(req.Tags)
+	if len(tags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "标签不能为空且长度不能超过64个字符",
+		})
+		return nil, nil, false
+	}
+	return &image, tags, true
+}
+
+// imageTagsResponse 返回图片当前的标签
+func imageTagsResponse(c *gin.Context, image *models.Image, msg string) {
+	images := []models.Image{*image}
+	services.TagSvc.Attach(images)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+		"data": gin.H{"tags": images[0].Tags},
+	})
+}
+
+// GetTagSuggestions 标签联想，按使用次数排序（只统计当前用户可浏览的图片）
+func GetTagSuggestions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	suggestions, err := services.TagSvc.Suggest(readableImages(c), c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "获取标签失败",
+		})
+		return
+	}
+	if suggestions == nil {
+		suggestions = []services.TagSuggestion{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取标签成功",
+		"data": suggestions,
+	})
+}
+
+// AddImageTags 给图片添加标签
+func AddImageTags(c *gin.Context) {
+	image, tags, ok := bindImageTags(c)
+	if !ok {
+		return
+	}
+
+	err := services.TagSvc.AddToImages([]int{image.Id}, tags)
+	var limitErr *services.TagLimitError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  limitErr.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "添加标签失败",
+		})
+		return
+	}
+	imageTagsResponse(c, image, "添加标签成功")
+}
+
+// RemoveImageTags 移除图片上的标签
+func RemoveImageTags(c *gin.Context) {
+	image, tags, ok := bindImageTags(c)
+	if !ok {
+		return
+	}
+
+	if err := services.TagSvc.RemoveFromImages([]int{image.Id}, tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "移除标签失败",
+		})
+		return
+	}
+	imageTagsResponse(c, image, "移除标签成功")
+}
+
+// RenameTag 重命名标签（标签全站共享，需要管理全部图片的权限）
+func RenameTag(c *gin.Context) {
+	tag, ok := findTag(c)
+	if !ok {
+		return
+	}
+
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	name := services.NormalizeTag(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "标签不能为空且长度不能超过64个字符",
+		})
+		return
+	}
+
+	if err := services.TagSvc.Rename(tag.Id, name); err != nil {
+		if err == services.ErrTagExists {
+			c.JSON(http.StatusConflict, gin.H{
+				"code": 409,
+				"msg":  "标签已存在，请使用合并",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "重命名标签失败",
+		})
+		return
+	}
+
+	tag.Name = name
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "重命名标签成功",
+		"data": tag,
+	})
+}
+
+// MergeTags 把多个标签合并到目标标签
+func MergeTags(c *gin.Context) {
+	var req MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	db := database.GetDB().DB
+	var target models.Tag
+	if err := db.First(&target, req.TargetId).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "目标标签不存在",
+		})
+		return
+	}
+
+	var sourceIDs []int
+	db.Model(&models.Tag{}).Where("id IN ? AND id <> ?", req.SourceIds, target.Id).Pluck("id", &sourceIDs)
+	if len(sourceIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "没有可合并的标签",
+		})
+		return
+	}
+
+	if err := services.TagSvc.Merge(sourceIDs, target.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "合并标签失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "合并标签成功",
+		"data": gin.H{"merged": len(sourceIDs), "target": target},
+	})
+}
+
+// DeleteTag 删除标签，图片本身不受影响
+func DeleteTag(c *gin.Context) {
+	tag, ok := findTag(c)
+	if !ok {
+		return
+	}
+
+	if err := services.TagSvc.Delete(tag.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除标签失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除标签成功",
+	})
+}
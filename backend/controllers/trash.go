@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashItem 回收站列表项
+type TrashItem struct {
+	models.Image
+	PurgeAt *time.Time `json:"purge_at"` // 自动彻底删除的时间，为空表示不自动清理
+}
+
+// trashedImages 当前用户有权管理的回收站图片
+func trashedImages(c *gin.Context) *gorm.DB {
+	return manageableImages(c).Unscoped().Where("images.deleted_at IS NOT NULL")
+}
+
+// findTrashedImage 读取路径中的回收站图片
+func findTrashedImage(c *gin.Context) (*models.Image, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的图片ID",
+		})
+		return nil, false
+	}
+
+	var image models.Image
+	if err := trashedImages(c).First(&image, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "回收站中没有该图片",
+		})
+		return nil, false
+	}
+	return &image, true
+}
+
+// GetTrashList 获取回收站图片列表，按删除时间倒序
+func GetTrashList(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total int64
+	if err := trashedImages(c).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "获取回收站失败",
+		})
+		return
+	}
+
+	var images []models.Image
+	if err := trashedImages(c).Order("images.deleted_at DESC, images.id DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "获取回收站失败",
+		})
+		return
+	}
+	services.TagSvc.Attach(images)
+	services.LinkSvc.Fill(requestBaseURL(c), images)
+
+	items := make([]TrashItem, 0, len(images))
+	for i := range images {
+		items = append(items, TrashItem{Image: images[i], PurgeAt: services.TrashSvc.PurgeAt(&images[i])})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取回收站成功",
+		"data": gin.H{
+			"images":      items,
+			"total":       total,
+			"page":        page,
+			"limit":       limit,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// RestoreImage 从回收站恢复图片，相册和标签关联随之恢复
+func RestoreImage(c *gin.Context) {
+	image, ok := findTrashedImage(c)
+	if !ok {
+		return
+	}
+
+	if err := trashedImages(c).Model(&models.Image{}).Where("images.id = ?", image.Id).
+		Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "恢复图片失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "恢复图片成功",
+	})
+}
+
+// DeleteTrashImage 彻底删除回收站中的图片（删除文件，不可恢复）
+func DeleteTrashImage(c *gin.Context) {
+	image, ok := findTrashedImage(c)
+	if !ok {
+		return
+	}
+
+	if err := services.TrashSvc.Destroy(image); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "彻底删除图片失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "图片已彻底删除",
+	})
+}
+
+// EmptyTrash 清空回收站（只清理当前用户有权管理的图片）
+func EmptyTrash(c *gin.Context) {
+	var images []models.Image
+	if err := trashedImages(c).Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "清空回收站失败",
+		})
+		return
+	}
+
+	destroyed, err := services.TrashSvc.DestroyAll(images)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "部分图片删除失败: " + err.Error(),
+			"data": gin.H{"deleted": destroyed, "failed": len(images) - destroyed},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "回收站已清空",
+		"data": gin.H{"deleted": destroyed},
+	})
+}
@@ -0,0 +1,478 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 密码验证通过后，提交两步验证码的有效期
+const twoFactorPendingTTL = 5 * time.Minute
+
+// TwoFactorResponse 两步验证响应结构
+type TwoFactorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// TwoFactorCodeRequest 提交验证码请求结构
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorPasswordRequest 需要当前密码确认的请求结构
+type TwoFactorPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginTwoFactor 登录第二步：校验TOTP验证码或恢复码
+func LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	session := sessions.Default(c)
+	pendingUserID, ok1 := session.Get("pending_user_id").(int)
+	pendingAt, ok2 := session.Get("pending_at").(int64)
+	if !ok1 || !ok2 || time.Since(time.Unix(pendingAt, 0)) > twoFactorPendingTTL {
+		session.Delete("pending_user_id")
+		session.Delete("pending_at")
+		session.Save()
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Code:    401,
+			Message: "两步验证已过期，请重新登录",
+			Success: false,
+		})
+		return
+	}
+
+	db := database.GetDB().DB
+	var user models.User
+	if err := db.First(&user, pendingUserID).Error; err != nil || !user.TotpEnabled {
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Code:    401,
+			Message: "两步验证已失效，请重新登录",
+			Success: false,
+		})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	// 两步验证码同样受防爆破限制
+	if wait := services.LoginGuardSvc.Check(clientIP, user.Username); wait > 0 {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, LoginResponse{
+			Code:       429,
+			Message:    "验证失败次数过多，请稍后重试",
+			Success:    false,
+			RetryAfter: retryAfter,
+		})
+		return
+	}
+
+	if !verifySecondFactor(db, &user, req.Code) {
+		services.LoginGuardSvc.RecordFailure(clientIP, user.Username, userAgent, "两步验证码错误")
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Code:    401,
+			Message: "验证码错误",
+			Success: false,
+		})
+		return
+	}
+
+	completeLogin(c, &user, clientIP, userAgent)
+}
+
+// verifySecondFactor 依次尝试TOTP验证码和未使用的恢复码
+func verifySecondFactor(db *gorm.DB, user *models.User, code string) bool {
+	if counter, ok := services.ValidateTOTP(user.TotpSecret, code, user.TotpLastCounter); ok {
+		// 记录已使用的时间步，防止同一验证码被重放
+		result := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_counter < ?", user.Id, counter).
+			Update("totp_last_counter", counter)
+		return result.Error == nil && result.RowsAffected == 1
+	}
+
+	normalized := services.NormalizeRecoveryCode(code)
+	var codes []models.RecoveryCode
+	db.Where("user_id = ? AND used_at IS NULL", user.Id).Find(&codes)
+	for _, rc := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), []byte(normalized)) != nil {
+			continue
+		}
+		now := time.Now()
+		result := db.Model(&models.RecoveryCode{}).
+			Where("id = ? AND used_at IS NULL", rc.Id).
+			Update("used_at", &now)
+		return result.Error == nil && result.RowsAffected == 1
+	}
+	return false
+}
+
+// currentUserRecord 读取当前登录用户的数据库记录
+func currentUserRecord(c *gin.Context) (*models.User, bool) {
+	userID, _, exists := middlewares.GetCurrentUser(c)
+	if !exists {
+		return nil, false
+	}
+
+	var user models.User
+	if err := database.GetDB().DB.First(&user, userID).Error; err != nil {
+		return nil, false
+	}
+	return &user, true
+}
+
+// GetTwoFactorStatus 获取两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	user, ok := currentUserRecord(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, TwoFactorResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return
+	}
+
+	var remaining int64
+	database.GetDB().DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", user.Id).
+		Count(&remaining)
+
+	c.JSON(http.StatusOK, TwoFactorResponse{
+		Code:    200,
+		Message: "获取两步验证状态成功",
+		Success: true,
+		Data: gin.H{
+			"enabled":                  user.TotpEnabled,
+			"remaining_recovery_codes": remaining,
+		},
+	})
+}
+
+// SetupTwoFactor 生成新的TOTP密钥，返回认证器地址和二维码
+// 密钥在调用 EnableTwoFactor 验证通过前不会生效
+func SetupTwoFactor(c *gin.Context) {
+	user, ok := currentUserRecord(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, TwoFactorResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return
+	}
+
+	if user.TotpEnabled {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "两步验证已开启，请先关闭后再重新绑定",
+			Success: false,
+		})
+		return
+	}
+
+	secret, err := services.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "生成密钥失败",
+			Success: false,
+		})
+		return
+	}
+
+	cfg := c.MustGet("config").(*config.Config)
+	uri := services.TOTPProvisioningURI(cfg.TotpIssuer, user.Username, secret)
+	png, err := services.TOTPQRCodePNG(uri)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "生成二维码失败",
+			Success: false,
+		})
+		return
+	}
+
+	if err := database.GetDB().DB.Model(user).Updates(map[string]any{
+		"totp_secret":       secret,
+		"totp_last_counter": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "保存密钥失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TwoFactorResponse{
+		Code:    200,
+		Message: "请使用认证器扫描二维码后提交验证码",
+		Success: true,
+		Data: gin.H{
+			"secret":           secret,
+			"provisioning_uri": uri,
+			"qr_code":          "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		},
+	})
+}
+
+// EnableTwoFactor 校验验证码后开启两步验证，并返回一次性恢复码
+func EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	user, ok := currentUserRecord(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, TwoFactorResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return
+	}
+
+	if user.TotpEnabled {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "两步验证已开启",
+			Success: false,
+		})
+		return
+	}
+	if user.TotpSecret == "" {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "请先生成两步验证密钥",
+			Success: false,
+		})
+		return
+	}
+
+	counter, valid := services.ValidateTOTP(user.TotpSecret, req.Code, 0)
+	if !valid {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "验证码错误",
+			Success: false,
+		})
+		return
+	}
+
+	codes, hashes, err := services.GenerateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "生成恢复码失败",
+			Success: false,
+		})
+		return
+	}
+
+	err = database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]any{
+			"totp_enabled":      true,
+			"totp_last_counter": counter,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, user.Id, hashes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "开启两步验证失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TwoFactorResponse{
+		Code:    200,
+		Message: "两步验证已开启，请妥善保存恢复码",
+		Success: true,
+		Data: gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor 验证当前密码后关闭两步验证
+func DisableTwoFactor(c *gin.Context) {
+	var req TwoFactorPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	user, ok := currentUserRecord(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, TwoFactorResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "当前密码错误",
+			Success: false,
+		})
+		return
+	}
+
+	err := database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]any{
+			"totp_enabled":      false,
+			"totp_secret":       "",
+			"totp_last_counter": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.Id).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "关闭两步验证失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TwoFactorResponse{
+		Code:    200,
+		Message: "两步验证已关闭",
+		Success: true,
+	})
+}
+
+// RegenerateRecoveryCodes 验证当前密码后重新生成恢复码，旧恢复码全部作废
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	user, ok := currentUserRecord(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, TwoFactorResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return
+	}
+
+	if !user.TotpEnabled {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "两步验证未开启",
+			Success: false,
+		})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusBadRequest, TwoFactorResponse{
+			Code:    400,
+			Message: "当前密码错误",
+			Success: false,
+		})
+		return
+	}
+
+	codes, hashes, err := services.GenerateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "生成恢复码失败",
+			Success: false,
+		})
+		return
+	}
+
+	err = database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, user.Id, hashes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TwoFactorResponse{
+			Code:    500,
+			Message: "保存恢复码失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TwoFactorResponse{
+		Code:    200,
+		Message: "恢复码已重新生成，请妥善保存",
+		Success: true,
+		Data: gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// replaceRecoveryCodes 删除用户现有恢复码并写入新的哈希
+func replaceRecoveryCodes(tx *gorm.DB, userID int, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	records := make([]models.RecoveryCode, 0, len(hashes))
+	for _, hash := range hashes {
+		records = append(records, models.RecoveryCode{
+			UserId:    userID,
+			CodeHash:  hash,
+			CreatedAt: now,
+		})
+	}
+	return tx.Create(&records).Error
+}
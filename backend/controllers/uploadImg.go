@@ -2,20 +2,24 @@ package controllers
 
 import (
 	"fmt"
+	"log"
 	"math/rand"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"oneimg/backend/config"
 	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
 	"oneimg/backend/models"
 	"oneimg/backend/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // UploadResponse 上传响应结构
@@ -29,14 +33,26 @@ type UploadResponse struct {
 type ImageResult struct {
 	Success   bool   `json:"success"`
 	Message   string `json:"message,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"` // 失败原因代码，如超出配额
 	ID        int    `json:"id,omitempty"`
 	URL       string `json:"url,omitempty"`
+	PublicURL string `json:"public_url,omitempty"` // 绝对链接（站点或CDN地址）
 	FileName  string `json:"filename,omitempty"`
 	FileSize  int64  `json:"file_size,omitempty"`
 	MimeType  string `json:"mime_type,omitempty"`
 	Width     int    `json:"width,omitempty"`
 	Height    int    `json:"height,omitempty"`
 	CreatedAt string `json:"created_at,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"` // 游客图片的过期时间
+	DeleteURL string `json:"delete_url,omitempty"` // 游客图片的删除链接
+}
+
+// uploadOwner 上传图片的归属信息
+type uploadOwner struct {
+	UserID      int        // 所属用户，游客上传为0
+	GuestIP     string     // 游客IP
+	ExpiresAt   *time.Time // 到期自动删除
+	DeleteToken string     // 删除链接密钥
 }
 
 // UploadImages 批量上传图片
@@ -98,12 +114,44 @@ func UploadImages(c *gin.Context) {
 		return
 	}
 
+	// 可选：上传后直接加入相册
+	albumID := 0
+	if values := form.Value["album_id"]; len(values) > 0 && values[0] != "" {
+		id, err := strconv.Atoi(values[0])
+		var count int64
+		if err == nil {
+			manageableAlbums(c).Where("id = ?", id).Count(&count)
+		}
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, UploadResponse{
+				Code:    400,
+				Message: "相册不存在",
+				Data:    []ImageResult{},
+			})
+			return
+		}
+		albumID = id
+	}
+
+	// 上传的图片归属于当前用户
+	userID, _, _ := middlewares.GetCurrentUser(c)
+
 	var results []ImageResult
+	var uploadedIDs []int
 
 	// 处理每个上传的文件
 	for _, fileHeader := range files {
-		result := processUploadFile(fileHeader, cfg, db)
+		result := processUploadFile(fileHeader, cfg, db, uploadOwner{UserID: userID}, requestBaseURL(c))
 		results = append(results, result)
+		if result.Success {
+			uploadedIDs = append(uploadedIDs, result.ID)
+		}
+	}
+
+	if albumID > 0 {
+		if err := services.AlbumSvc.AddImages(albumID, uploadedIDs); err != nil {
+			log.Printf("上传图片加入相册失败: %v", err)
+		}
 	}
 
 	// 统计成功和失败的数量
@@ -124,7 +172,8 @@ func UploadImages(c *gin.Context) {
 }
 
 // processUploadFile 处理单个上传文件
-func processUploadFile(fileHeader *multipart.FileHeader, cfg *config.Config, db *database.Database) ImageResult {
+// requestBase 为根据请求推断的站点地址，未配置图片地址时只用于生成响应中的绝对链接，不写入数据库
+func processUploadFile(fileHeader *multipart.FileHeader, cfg *config.Config, db *database.Database, owner uploadOwner, requestBase string) ImageResult {
 	// 验证图片
 	if err := services.ImageSvc.ValidateImage(fileHeader, cfg.AllowedTypes, cfg.MaxFileSize); err != nil {
 		return ImageResult{
@@ -174,6 +223,25 @@ func processUploadFile(fileHeader *multipart.FileHeader, cfg *config.Config, db
 	// 构建文件路径
 	filePath := filepath.Join(subDir, uniqueFileName)
 
+	// 检查配额，检查到入库期间持有该用户的锁，防止并发上传超出配额（游客上传另有限制）
+	if owner.UserID > 0 {
+		unlock := services.QuotaSvc.Lock(owner.UserID)
+		defer unlock()
+		if err := services.QuotaSvc.Check(owner.UserID, int64(len(processedImage.CompressedBytes))); err != nil {
+			if quotaErr, ok := err.(*services.QuotaError); ok {
+				return ImageResult{
+					Success:   false,
+					Message:   "超出配额: " + quotaErr.Message,
+					ErrorCode: quotaErr.Code,
+				}
+			}
+			return ImageResult{
+				Success: false,
+				Message: "检查配额失败: " + err.Error(),
+			}
+		}
+	}
+
 	// 保存处理后的图片文件
 	if err := saveFile(filePath, processedImage.CompressedBytes); err != nil {
 		return ImageResult{
@@ -187,29 +255,49 @@ func processUploadFile(fileHeader *multipart.FileHeader, cfg *config.Config, db
 
 	// 保存到数据库
 	imageModel := models.Image{
-		Url:       fileURL,
-		FileName:  uniqueFileName,
-		FileSize:  int64(len(processedImage.CompressedBytes)),
-		MimeType:  processedImage.MimeType,
-		Width:     processedImage.Width,
-		Height:    processedImage.Height,
-		CreatedAt: time.Now(),
+		UserId:       owner.UserID,
+		ExpiresAt:    owner.ExpiresAt,
+		DeleteToken:  owner.DeleteToken,
+		UploaderIP:   owner.GuestIP,
+		Url:          fileURL,
+		PublicUrl:    services.LinkSvc.StoredURL(&models.Image{Url: fileURL}),
+		FileName:     uniqueFileName,
+		OriginalName: originalFileName(fileHeader.Filename),
+		FileSize:     int64(len(processedImage.CompressedBytes)),
+		ContentHash:  services.ContentHash(processedImage.CompressedBytes),
+		MimeType:     processedImage.MimeType,
+		Width:        processedImage.Width,
+		Height:       processedImage.Height,
+		CameraMake:   processedImage.Exif.CameraMake,
+		CameraModel:  processedImage.Exif.CameraModel,
+		LensModel:    processedImage.Exif.LensModel,
+		TakenAt:      processedImage.Exif.TakenAt,
+		CreatedAt:    time.Now(),
 	}
 
-	result := db.DB.Create(&imageModel)
-	if result.Error != nil {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&imageModel).Error; err != nil {
+			return err
+		}
+		return services.SearchSvc.Index(tx, []int{imageModel.Id})
+	})
+	if err != nil {
 		// 如果数据库保存失败，删除已保存的文件
 		os.Remove(filePath)
 		return ImageResult{
 			Success: false,
-			Message: "保存到数据库失败: " + result.Error.Error(),
+			Message: "保存到数据库失败: " + err.Error(),
 		}
 	}
 
+	// 开启格式协商时在后台预先生成备用格式
+	services.AlternateSvc.Enqueue(&imageModel)
+
 	return ImageResult{
 		Success:   true,
 		ID:        imageModel.Id,
 		URL:       imageModel.Url,
+		PublicURL: services.LinkSvc.ImageURL(requestBase, &imageModel),
 		FileName:  imageModel.FileName,
 		FileSize:  imageModel.FileSize,
 		MimeType:  imageModel.MimeType,
@@ -240,6 +328,18 @@ func generateUniqueFileName(ext string) string {
 	return fmt.Sprintf("%s%d%s", hash, randomNum, ext)
 }
 
+// originalFileName 取客户端上传的文件名（去掉路径，最长255个字符）
+func originalFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
 // determineOutputFormat 确定输出格式
 func determineOutputFormat(contentType, originalExt string) string {
 	// 保持原格式的特殊类型
@@ -316,7 +416,8 @@ func UploadImage(c *gin.Context) {
 	}
 
 	// 处理单个文件
-	result := processUploadFile(header, cfg, db)
+	userID, _, _ := middlewares.GetCurrentUser(c)
+	result := processUploadFile(header, cfg, db, uploadOwner{UserID: userID}, requestBaseURL(c))
 
 	if result.Success {
 		c.JSON(http.StatusOK, gin.H{
@@ -326,9 +427,10 @@ func UploadImage(c *gin.Context) {
 		})
 	} else {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": result.Message,
-			"data":    []string{},
+			"code":       400,
+			"message":    result.Message,
+			"error_code": result.ErrorCode,
+			"data":       []string{},
 		})
 	}
 }
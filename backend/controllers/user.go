@@ -3,6 +3,9 @@ package controllers
 import (
 	"net/http"
 
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -18,11 +21,17 @@ func CheckLoginStatus(c *gin.Context) {
 	// 经过了OptionalAuthMiddleware，这里一定已经登录了
 	session := sessions.Default(c)
 	userID := session.Get("user_id")
+
+	var user models.User
+	database.GetDB().DB.First(&user, userID)
+
 	c.JSON(http.StatusOK, UserInfoResponse{
 		Code:    200,
 		Message: "已登录",
 		Data: map[string]any{
 			"user_id":   userID,
+			"username":  user.Username,
+			"role":      user.Role,
 			"logged_in": true,
 		},
 	})
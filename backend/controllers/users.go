@@ -0,0 +1,389 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/middlewares"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// UserListItem 用户列表项（含图片用量）
+type UserListItem struct {
+	models.User
+	ImageCount int64 `json:"image_count"`
+	TotalSize  int64 `json:"total_size"`
+}
+
+// CreateUserRequest 创建用户请求结构
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=20"`
+	Password string `json:"password" binding:"required,min=6"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin uploader viewer"`
+}
+
+// UpdateUserRoleRequest 修改用户角色请求结构
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin uploader viewer"`
+}
+
+// UpdateUserStatusRequest 启用/禁用用户请求结构
+type UpdateUserStatusRequest struct {
+	Disabled bool `json:"disabled"`
+}
+
+// ListUsers 获取所有用户
+func ListUsers(c *gin.Context) {
+	db := database.GetDB().DB
+
+	var users []models.User
+	if err := db.Order("id ASC").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "获取用户列表失败",
+			Success: false,
+		})
+		return
+	}
+
+	// 按用户汇总图片数量和大小
+	var usage []struct {
+		UserId int
+		Count  int64
+		Size   int64
+	}
+	db.Model(&models.Image{}).
+		Select("user_id, COUNT(*) as count, COALESCE(SUM(file_size), 0) as size").
+		Group("user_id").
+		Scan(&usage)
+
+	usageByUser := make(map[int]int, len(usage))
+	for i, u := range usage {
+		usageByUser[u.UserId] = i
+	}
+
+	list := make([]UserListItem, 0, len(users))
+	for _, user := range users {
+		item := UserListItem{User: user}
+		if i, ok := usageByUser[user.Id]; ok {
+			item.ImageCount = usage[i].Count
+			item.TotalSize = usage[i].Size
+		}
+		list = append(list, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取用户列表成功",
+		"success": true,
+		"data":    list,
+	})
+}
+
+// CreateUser 创建用户
+func CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	db := database.GetDB().DB
+
+	var count int64
+	db.Model(&models.User{}).Where("username = ?", req.Username).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "用户名已存在",
+			Success: false,
+		})
+		return
+	}
+
+	if err := services.PasswordSvc.Validate(req.Password, req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "密码加密失败",
+			Success: false,
+		})
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoleUploader
+	}
+
+	user := models.User{
+		Username: req.Username,
+		Password: string(hashedPassword),
+		Role:     role,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "创建用户失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "创建用户成功",
+		"success": true,
+		"data":    user,
+	})
+}
+
+// UpdateUserStatus 启用或禁用用户，禁用后该用户的所有会话立即失效
+func UpdateUserStatus(c *gin.Context) {
+	target, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	if req.Disabled && target.Role == models.RoleAdmin && isLastActiveAdmin(target.Id) {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "不能禁用最后一个管理员",
+			Success: false,
+		})
+		return
+	}
+
+	if err := database.GetDB().DB.Model(target).Update("disabled", req.Disabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "更新用户状态失败",
+			Success: false,
+		})
+		return
+	}
+
+	if req.Disabled {
+		services.SessionSvc.RevokeAll(target.Id, 0)
+	}
+
+	message := "用户已启用"
+	if req.Disabled {
+		message = "用户已禁用"
+	}
+	c.JSON(http.StatusOK, AccountResponse{
+		Code:    200,
+		Message: message,
+		Success: true,
+	})
+}
+
+// UpdateUserRole 修改用户角色
+func UpdateUserRole(c *gin.Context) {
+	target, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	if target.Role == models.RoleAdmin && req.Role != models.RoleAdmin && isLastActiveAdmin(target.Id) {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "不能取消最后一个管理员的权限",
+			Success: false,
+		})
+		return
+	}
+
+	if err := database.GetDB().DB.Model(target).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "更新用户角色失败",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AccountResponse{
+		Code:    200,
+		Message: "用户角色已更新",
+		Success: true,
+	})
+}
+
+// DeleteUser 删除用户
+// 传入 transfer_to 时将其图片转移给指定用户，否则一并删除其图片
+func DeleteUser(c *gin.Context) {
+	target, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if target.Role == models.RoleAdmin && isLastActiveAdmin(target.Id) {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "不能删除最后一个管理员",
+			Success: false,
+		})
+		return
+	}
+
+	db := database.GetDB().DB
+
+	transferTo := 0
+	if value := c.Query("transfer_to"); value != "" {
+		id, err := strconv.Atoi(value)
+		var receiver models.User
+		if err != nil || id == target.Id || db.First(&receiver, id).Error != nil {
+			c.JSON(http.StatusBadRequest, AccountResponse{
+				Code:    400,
+				Message: "无效的图片接收用户",
+				Success: false,
+			})
+			return
+		}
+		transferTo = id
+	}
+
+	// 不转移时需要在删除记录后清理文件（包括回收站中的图片）
+	var images []models.Image
+	if transferTo == 0 {
+		db.Unscoped().Where("user_id = ?", target.Id).Find(&images)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if transferTo > 0 {
+			if err := tx.Unscoped().Model(&models.Image{}).Where("user_id = ?", target.Id).Update("user_id", transferTo).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Album{}).Where("user_id = ?", target.Id).Update("user_id", transferTo).Error; err != nil {
+				return err
+			}
+		} else {
+			imageIDs := make([]int, 0, len(images))
+			for _, image := range images {
+				imageIDs = append(imageIDs, image.Id)
+			}
+			if err := services.ImageSvc.DetachRelations(tx, imageIDs); err != nil {
+				return err
+			}
+			if err := tx.Where("album_id IN (?)", tx.Model(&models.Album{}).Select("id").Where("user_id = ?", target.Id)).
+				Delete(&models.AlbumImage{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ?", target.Id).Delete(&models.Album{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", target.Id).Delete(&models.Image{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ?", target.Id).Delete(&models.UserSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", target.Id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(target).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AccountResponse{
+			Code:    500,
+			Message: "删除用户失败",
+			Success: false,
+		})
+		return
+	}
+
+	cfg := c.MustGet("config").(*config.Config)
+	for i := range images {
+		removeImageFile(cfg, &images[i])
+	}
+
+	c.JSON(http.StatusOK, AccountResponse{
+		Code:    200,
+		Message: "删除用户成功",
+		Success: true,
+	})
+}
+
+// findManagedUser 读取路径中的目标用户，管理员不能对自己执行这些操作
+func findManagedUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "无效的用户ID",
+			Success: false,
+		})
+		return nil, false
+	}
+
+	if currentID, _, _ := middlewares.GetCurrentUser(c); currentID == id {
+		c.JSON(http.StatusBadRequest, AccountResponse{
+			Code:    400,
+			Message: "不能对当前登录的账号执行此操作",
+			Success: false,
+		})
+		return nil, false
+	}
+
+	var user models.User
+	if err := database.GetDB().DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, AccountResponse{
+			Code:    404,
+			Message: "用户不存在",
+			Success: false,
+		})
+		return nil, false
+	}
+	return &user, true
+}
+
+// isLastActiveAdmin 判断指定用户是否为唯一未被禁用的管理员
+func isLastActiveAdmin(userID int) bool {
+	var count int64
+	database.GetDB().DB.Model(&models.User{}).
+		Where("role = ? AND disabled = ? AND id <> ?", models.RoleAdmin, false, userID).
+		Count(&count)
+	return count == 0
+}
@@ -64,10 +64,28 @@ func InitDB(cfg *config.Config) {
 	log.Println("数据库连接成功")
 
 	// 自动迁移数据表
-	err = db.DB.AutoMigrate(&models.User{}, &models.Image{})
+	err = db.DB.AutoMigrate(
+		&models.User{},
+		&models.Image{},
+		&models.LoginAttempt{},
+		&models.LoginLock{},
+		&models.RecoveryCode{},
+		&models.UserSession{},
+		&models.Invitation{},
+		&models.GuestUpload{},
+		&models.Album{},
+		&models.AlbumImage{},
+		&models.Tag{},
+		&models.ImageTag{},
+		&models.ShareLink{},
+		&models.Migration{},
+	)
 	if err != nil {
 		log.Fatal("数据库迁移失败:", err)
 	}
 
 	log.Println("数据库表迁移完成")
+
+	// 根据数据库类型初始化全文检索
+	initSearch(cfg.IsMysql)
 }
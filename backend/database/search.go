@@ -0,0 +1,75 @@
+package database
+
+import (
+	"log"
+)
+
+// 全文检索方式
+const (
+	SearchFTS5     = "fts5"     // SQLite FTS5 虚拟表 images_fts
+	SearchFulltext = "fulltext" // MySQL FULLTEXT 索引表 image_search
+	SearchLike     = "like"     // 不支持全文检索时退回 LIKE 匹配
+)
+
+var searchMode = SearchLike
+
+// SearchMode 返回当前数据库使用的全文检索方式
+func SearchMode() string {
+	return searchMode
+}
+
+// initSearch 按数据库类型创建全文检索索引，失败时退回 LIKE 匹配
+// 索引列：name（原始文件名和存储文件名）、title、description、alt_text、tags、exif
+func initSearch(isMysql bool) {
+	if isMysql {
+		initMysqlFulltext()
+	} else {
+		initSqliteFTS5()
+	}
+
+	if searchMode == SearchLike {
+		log.Println("全文检索不可用，搜索将使用 LIKE 匹配")
+	} else {
+		log.Printf("全文检索已启用: %s", searchMode)
+	}
+}
+
+// initSqliteFTS5 创建 FTS5 虚拟表，rowid 与图片ID一致
+// 需要使用 -tags sqlite_fts5 编译 go-sqlite3
+func initSqliteFTS5() {
+	err := db.DB.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS images_fts USING fts5(
+		name, title, description, alt_text, tags, exif,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`).Error
+	if err != nil {
+		log.Printf("创建FTS5索引失败（编译时需要 -tags sqlite_fts5）: %v", err)
+		return
+	}
+	searchMode = SearchFTS5
+}
+
+// initMysqlFulltext 创建带 FULLTEXT 索引的检索表，优先使用 ngram 分词以支持中文
+func initMysqlFulltext() {
+	columns := `image_id INT NOT NULL PRIMARY KEY,
+		name VARCHAR(512) NOT NULL DEFAULT '',
+		title VARCHAR(200) NOT NULL DEFAULT '',
+		description TEXT,
+		alt_text VARCHAR(500) NOT NULL DEFAULT '',
+		tags TEXT,
+		exif VARCHAR(320) NOT NULL DEFAULT '',
+		FULLTEXT INDEX idx_image_search (name, title, description, alt_text, tags, exif)`
+
+	err := db.DB.Exec("CREATE TABLE IF NOT EXISTS image_search (" + columns +
+		" WITH PARSER ngram) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4").Error
+	if err != nil {
+		// MariaDB 等不支持 ngram 分词时使用默认分词
+		log.Printf("创建ngram全文索引失败，使用默认分词: %v", err)
+		err = db.DB.Exec("CREATE TABLE IF NOT EXISTS image_search (" + columns +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4").Error
+	}
+	if err != nil {
+		log.Printf("创建全文索引失败: %v", err)
+		return
+	}
+	searchMode = SearchFulltext
+}
@@ -0,0 +1,18 @@
+package database
+
+import "time"
+
+// Location 存储和查询时间使用的时区
+// SQLite 按字符串比较时间，查询条件需要与存储时使用相同的时区
+var Location = time.Local
+
+// QueryTime 把用于查询条件的时间转换为存储使用的时区
+func QueryTime(t time.Time) time.Time {
+	return t.In(Location)
+}
+
+// DayStart 存储时区中 t 所在日期的零点
+func DayStart(t time.Time) time.Time {
+	t = QueryTime(t)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, Location)
+}
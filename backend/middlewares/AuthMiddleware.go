@@ -3,6 +3,10 @@ package middlewares
 import (
 	"net/http"
 
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+	"oneimg/backend/services"
+
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -43,17 +47,53 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息存储到上下文中，供后续处理使用
-		session.Set("logged_in", true)
+		// 校验服务端会话记录，已被吊销或过期的会话视为未登录
+		record, valid := validateSessionRecord(c, session, userID)
+		if !valid {
+			session.Clear()
+			session.Save()
+			c.JSON(http.StatusUnauthorized, AuthResponse{
+				Code:    401,
+				Message: "会话已失效，请重新登录",
+			})
+			c.Abort()
+			return
+		}
 
+		// 读取用户角色，已禁用的用户视为未登录
+		var user models.User
+		if err := database.GetDB().DB.First(&user, record.UserId).Error; err != nil || user.Disabled {
+			session.Clear()
+			session.Save()
+			c.JSON(http.StatusUnauthorized, AuthResponse{
+				Code:    401,
+				Message: "账号不可用，请重新登录",
+			})
+			c.Abort()
+			return
+		}
+
+		// 将用户信息存储到上下文中，供后续处理使用
 		c.Set("user_id", userID)
 		c.Set("username", username)
+		c.Set("session_id", record.Id)
+		c.Set("role", user.Role)
 
 		// 继续处理请求
 		c.Next()
 	}
 }
 
+// validateSessionRecord 校验session中的会话token是否对应有效的服务端会话
+func validateSessionRecord(c *gin.Context, session sessions.Session, userID any) (*models.UserSession, bool) {
+	id, ok := userID.(int)
+	if !ok {
+		return nil, false
+	}
+	token, _ := session.Get("session_token").(string)
+	return services.SessionSvc.Validate(id, token, c.ClientIP())
+}
+
 // OptionalAuthMiddleware 可选认证中间件（不强制要求认证）
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -68,9 +108,16 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			username := session.Get("username")
 
 			if userID != nil && username != nil {
-				// 将用户信息存储到上下文中
-				c.Set("user_id", userID)
-				c.Set("username", username)
+				if record, valid := validateSessionRecord(c, session, userID); valid {
+					var user models.User
+					if err := database.GetDB().DB.First(&user, record.UserId).Error; err == nil && !user.Disabled {
+						// 将用户信息存储到上下文中
+						c.Set("user_id", userID)
+						c.Set("username", username)
+						c.Set("session_id", record.Id)
+						c.Set("role", user.Role)
+					}
+				}
 			}
 		}
 
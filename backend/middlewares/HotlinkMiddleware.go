@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"oneimg/backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HotlinkMiddleware 防盗链中间件，按 Referer 的域名匹配黑白名单
+// 本站页面（包括配置的站点地址和图片地址）和带有效签名的请求（需在 SignedURLMiddleware 之后使用）始终放行
+// 拦截时返回配置的替代图片，未配置时返回403
+func HotlinkMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.HotlinkProtection || c.GetBool("signed_url") {
+			c.Next()
+			return
+		}
+		// 响应内容取决于来源，避免共享缓存把放行的响应提供给盗链方
+		c.Header("Vary", "Referer")
+		if refererAllowed(cfg, c.Request) {
+			c.Next()
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		if cfg.HotlinkPlaceholder != "" {
+			c.File(cfg.HotlinkPlaceholder)
+		} else {
+			c.String(http.StatusForbidden, "禁止盗链")
+		}
+		c.Abort()
+	}
+}
+
+// refererAllowed 判断请求来源是否允许访问图片
+func refererAllowed(cfg *config.Config, r *http.Request) bool {
+	referer := r.Header.Get("Referer")
+	if referer == "" {
+		return cfg.HotlinkAllowEmpty
+	}
+	u, err := url.Parse(referer)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if isOwnHost(cfg, r, host) {
+		return true
+	}
+	if matchDomain(cfg.HotlinkDenied, host) {
+		return false
+	}
+	return len(cfg.HotlinkAllowed) == 0 || matchDomain(cfg.HotlinkAllowed, host)
+}
+
+// isOwnHost 判断来源是否为本站：当前请求的域名、PUBLIC_BASE_URL 和 IMAGE_BASE_URL 的域名
+// 图片走CDN或独立域名时，本站页面的来源与图片请求的域名不同，同样需要放行
+func isOwnHost(cfg *config.Config, r *http.Request, host string) bool {
+	if hostOnly(r.Host) == host {
+		return true
+	}
+	bases := []string{cfg.PublicBaseURL}
+	for _, base := range cfg.ImageBaseURLs {
+		bases = append(bases, base)
+	}
+	for _, base := range bases {
+		if u, err := url.Parse(base); err == nil && u.Hostname() != "" && strings.ToLower(u.Hostname()) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain 判断域名是否匹配列表中的任一规则，*.example.com 匹配所有子域名，* 匹配全部
+func matchDomain(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOnly 去掉Host中的端口
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(hostport)
+}
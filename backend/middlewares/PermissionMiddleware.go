@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"oneimg/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 权限定义
+const (
+	PermAccount        = "account"          // 管理自己的账号、会话和两步验证
+	PermImageView      = "image:view"       // 浏览图片和统计
+	PermImageViewAll   = "image:view_all"   // 浏览所有用户的图片
+	PermImageUpload    = "image:upload"     // 上传图片
+	PermImageManage    = "image:manage"     // 修改、删除自己的图片
+	PermImageManageAll = "image:manage_all" // 修改、删除所有用户的图片
+	PermUserManage     = "user:manage"      // 管理用户
+	PermSecurityManage = "security:manage"  // 管理登录安全设置
+)
+
+// rolePermissions 角色拥有的权限
+var rolePermissions = map[string][]string{
+	models.RoleAdmin: {
+		PermAccount,
+		PermImageView, PermImageViewAll,
+		PermImageUpload,
+		PermImageManage, PermImageManageAll,
+		PermUserManage,
+		PermSecurityManage,
+	},
+	models.RoleUploader: {
+		PermAccount,
+		PermImageView,
+		PermImageUpload,
+		PermImageManage,
+	},
+	models.RoleViewer: {
+		PermAccount,
+		PermImageView, PermImageViewAll,
+	},
+}
+
+// RoleHasPermission 判断角色是否拥有指定权限
+func RoleHasPermission(role, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// HasPermission 判断当前用户是否拥有指定权限
+func HasPermission(c *gin.Context, permission string) bool {
+	return RoleHasPermission(c.GetString("role"), permission)
+}
+
+// PermissionMiddleware 权限检查中间件，需在AuthMiddleware之后使用
+func PermissionMiddleware(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, AuthResponse{
+				Code:    403,
+				Message: "没有权限执行此操作",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,10 +1,16 @@
 package middlewares
 
 import (
+	"log"
+	"strconv"
+
 	"oneimg/backend/config"
+	"oneimg/backend/database"
 
 	"github.com/gin-contrib/sessions"
+	gormsessions "github.com/gin-contrib/sessions/gorm"
 	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-contrib/sessions/redis"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,20 +18,40 @@ var SessionStore sessions.Store
 
 // SessionMiddleware 配置session中间件
 func SessionMiddleware(cfg *config.Config) gin.HandlerFunc {
-	// 使用cookie存储session
-	// 生产环境建议使用Redis等外部存储
-	SessionStore = memstore.NewStore(
-		[]byte(cfg.SessionSecret),
-	)
+	SessionStore = newSessionStore(cfg)
 
 	// 配置session选项
 	SessionStore.Options(sessions.Options{
-		MaxAge:   24 * 60 * 60, // 24小时，单位秒
-		HttpOnly: true,         // 防止XSS攻击
-		Secure:   false,        // 生产环境应设为true（需要HTTPS）
-		SameSite: 4,            // SameSiteStrictMode，防止CSRF攻击
-		Path:     "/",          // cookie路径
+		MaxAge:   int(cfg.SessionMaxAge.Seconds()), // 默认24小时，单位秒
+		HttpOnly: true,                             // 防止XSS攻击
+		Secure:   false,                            // 生产环境应设为true（需要HTTPS）
+		SameSite: 4,                                // SameSiteStrictMode，防止CSRF攻击
+		Path:     "/",                              // cookie路径
 	})
 
 	return sessions.Sessions("oneimg-session", SessionStore)
 }
+
+// newSessionStore 根据配置创建session存储
+// database: 使用现有的SQLite/MySQL数据库，重启后会话不丢失，并定期清理过期会话
+// redis: 使用Redis存储，适合多副本部署，过期由Redis自动清理
+// memory: 进程内存储，重启后所有会话失效
+func newSessionStore(cfg *config.Config) sessions.Store {
+	secret := []byte(cfg.SessionSecret)
+
+	switch cfg.SessionStore {
+	case "memory":
+		log.Println("Session存储: 内存")
+		return memstore.NewStore(secret)
+	case "redis":
+		store, err := redis.NewStoreWithDB(10, "tcp", cfg.RedisAddr, cfg.RedisUsername, cfg.RedisPassword, strconv.Itoa(cfg.RedisDB), secret)
+		if err != nil {
+			log.Fatal("连接Redis失败:", err)
+		}
+		log.Printf("Session存储: Redis (%s)", cfg.RedisAddr)
+		return store
+	default:
+		log.Println("Session存储: 数据库")
+		return gormsessions.NewStore(database.GetDB().DB, true, secret)
+	}
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"oneimg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SignedURLMiddleware 签名URL校验中间件
+// 带 signature 参数的请求校验签名和有效期，通过后在上下文中设置 signed_url，失败返回403
+// 不带签名的请求直接放行，由后续处理按登录状态判断
+func SignedURLMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		if !query.Has(services.SignedURLSignature) {
+			c.Next()
+			return
+		}
+
+		if err := services.SignedURLSvc.Verify(c.Request.URL.Path, query); err != nil {
+			msg := "签名无效"
+			if errors.Is(err, services.ErrSignatureExpired) {
+				msg = "链接已过期"
+			}
+			c.String(http.StatusForbidden, msg)
+			c.Abort()
+			return
+		}
+
+		c.Set("signed_url", true)
+		c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 相册模型
+type Album struct {
+	Id           int       `json:"id" gorm:"primaryKey"`
+	UserId       int       `json:"user_id" gorm:"index"`
+	Name         string    `json:"name" gorm:"size:100;not null"`
+	Description  string    `json:"description" gorm:"size:500"`
+	CoverImageId *int      `json:"cover_image_id"` // 为空时使用相册中的第一张图片
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// 相册与图片的关联（多对多），Position 为图片在相册中的顺序
+type AlbumImage struct {
+	AlbumId   int       `json:"album_id" gorm:"primaryKey"`
+	ImageId   int       `json:"image_id" gorm:"primaryKey;index"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
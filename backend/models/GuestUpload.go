@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// 游客上传记录（用于按IP限流，图片删除后记录仍保留到统计窗口结束）
+type GuestUpload struct {
+	Id        int       `json:"id" gorm:"primaryKey"`
+	IP        string    `json:"ip" gorm:"size:64;index;not null"`
+	FileSize  int64     `json:"file_size"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -1,15 +1,50 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // 图片模型
 type Image struct {
 	Id        int       `json:"id" gorm:"primaryKey"`
-	Url       string    `json:"url" gorm:"not null"`
+	UserId    int       `json:"user_id" gorm:"index"`
+	Url       string    `json:"url" gorm:"not null;size:255;uniqueIndex"` // 相对路径，如 /uploads/2025/09/xxx.webp
+	PublicUrl string    `json:"public_url" gorm:"size:512"`               // 对外的绝对链接（站点或CDN地址 + Url）
 	FileName  string    `json:"filename" gorm:"not null"`
 	FileSize  int64     `json:"file_size" gorm:"not null"`
 	MimeType  string    `json:"mimeType"`
 	Width     int       `json:"width"`
 	Height    int       `json:"height"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// 文件内容的SHA-256，用作ETag（图片上传后不会修改）
+	ContentHash string `json:"content_hash" gorm:"size:64"`
+
+	// 可见性：私有图片不对外公开
+	Private bool `json:"private" gorm:"not null;default:false;index"`
+
+	// 软删除：删除的图片进入回收站，默认查询会自动排除
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// 图片信息（可编辑），OriginalName 为上传时的原始文件名
+	OriginalName string `json:"original_name" gorm:"size:255"`
+	Title        string `json:"title" gorm:"size:200"`
+	Description  string `json:"description" gorm:"size:2000"`
+	AltText      string `json:"alt_text" gorm:"size:500"`
+
+	// 上传时从原图读取的EXIF信息
+	CameraMake  string     `json:"camera_make" gorm:"size:100"`
+	CameraModel string     `json:"camera_model" gorm:"size:100"`
+	LensModel   string     `json:"lens_model" gorm:"size:100"`
+	TakenAt     *time.Time `json:"taken_at"`
+
+	// 游客上传（UserId 为0）
+	ExpiresAt   *time.Time `json:"expires_at" gorm:"index"` // 到期后自动删除，为空表示永久保留
+	DeleteToken string     `json:"-" gorm:"size:64;index"`  // 删除链接中的密钥
+	UploaderIP  string     `json:"-" gorm:"size:64"`
+
+	Tags       []string          `json:"tags" gorm:"-"`                 // 由 TagSvc.Attach 填充
+	Highlights map[string]string `json:"highlights,omitempty" gorm:"-"` // 搜索时由 SearchSvc.Highlight 填充
 }
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// 邀请码（管理员生成，用于新用户注册）
+type Invitation struct {
+	Id        int        `json:"id" gorm:"primaryKey"`
+	Code      string     `json:"code" gorm:"uniqueIndex;size:64;not null"`
+	Role      string     `json:"role" gorm:"size:16;not null"` // 注册后分配的角色
+	MaxUses   int        `json:"max_uses"`                     // 0表示不限次数
+	Uses      int        `json:"uses"`
+	Note      string     `json:"note" gorm:"size:255"`
+	CreatedBy int        `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"` // 为空表示永不过期
+}
+
+// IsUsable 判断邀请码当前是否仍可使用
+func (i *Invitation) IsUsable(now time.Time) bool {
+	if i.ExpiresAt != nil && now.After(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// 登录尝试审计记录
+type LoginAttempt struct {
+	Id        int       `json:"id" gorm:"primaryKey"`
+	Username  string    `json:"username" gorm:"index;size:191"`
+	IP        string    `json:"ip" gorm:"index;size:64"`
+	UserAgent string    `json:"user_agent" gorm:"size:512"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
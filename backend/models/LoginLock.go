@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// 登录锁定状态（按IP或用户名统计失败次数）
+type LoginLock struct {
+	Id            int       `json:"id" gorm:"primaryKey"`
+	Kind          string    `json:"kind" gorm:"size:16;not null;uniqueIndex:idx_login_lock_key"` // ip / user
+	Value         string    `json:"value" gorm:"size:191;not null;uniqueIndex:idx_login_lock_key"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
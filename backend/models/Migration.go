@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// 已执行的一次性数据迁移记录
+type Migration struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:191"`
+	AppliedAt time.Time `json:"applied_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// 两步验证恢复码（仅保存哈希，每个只能使用一次）
+type RecoveryCode struct {
+	Id        int        `json:"id" gorm:"primaryKey"`
+	UserId    int        `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
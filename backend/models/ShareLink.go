@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// 图片分享链接（可设置有效期、访问次数和密码）
+type ShareLink struct {
+	Id           int        `json:"id" gorm:"primaryKey"`
+	ImageId      int        `json:"image_id" gorm:"index;not null"`
+	Token        string     `json:"token" gorm:"uniqueIndex;size:64;not null"`
+	PasswordHash string     `json:"-" gorm:"size:255"` // 为空表示无需密码
+	MaxViews     int        `json:"max_views"`         // 0表示不限次数
+	Views        int        `json:"views"`
+	CreatedBy    int        `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at"` // 为空表示永不过期
+}
+
+// IsUsable 判断分享链接当前是否仍可访问
+func (l *ShareLink) IsUsable(now time.Time) bool {
+	if l.ExpiresAt != nil && now.After(*l.ExpiresAt) {
+		return false
+	}
+	return l.MaxViews == 0 || l.Views < l.MaxViews
+}
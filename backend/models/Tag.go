@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// 标签模型（全站共享，名称统一为小写）
+type Tag struct {
+	Id        int       `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 图片与标签的关联（多对多）
+type ImageTag struct {
+	ImageId   int       `json:"image_id" gorm:"primaryKey"`
+	TagId     int       `json:"tag_id" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
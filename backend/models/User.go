@@ -1,8 +1,39 @@
 package models
 
+import "time"
+
+// 用户角色
+const (
+	RoleAdmin    = "admin"    // 管理用户和系统设置，可管理所有图片
+	RoleUploader = "uploader" // 上传并管理自己的图片
+	RoleViewer   = "viewer"   // 只能浏览图片
+)
+
 // 用户模型
 type User struct {
-	Id       int    `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Id        int       `json:"id"`
+	Username  string    `json:"username" gorm:"uniqueIndex;size:191"`
+	Password  string    `json:"-"`
+	Role      string    `json:"role" gorm:"size:16;not null;default:uploader"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 两步验证（TOTP）
+	TotpSecret      string `json:"-"`
+	TotpEnabled     bool   `json:"totp_enabled"`
+	TotpLastCounter int64  `json:"-"` // 最近一次使用的时间步，防止验证码重放
+
+	// 个人配额，为空时使用角色默认配额，0表示不限制
+	QuotaBytes  *int64 `json:"quota_bytes"`
+	QuotaImages *int64 `json:"quota_images"`
+	QuotaDaily  *int64 `json:"quota_daily"`
+
+	// 单点登录身份，本地账号为空；通过SSO创建的账号没有本地密码
+	OidcIssuer  string `json:"-" gorm:"size:191;index:idx_users_oidc"`
+	OidcSubject string `json:"-" gorm:"size:191;index:idx_users_oidc"`
+}
+
+// IsValidRole 判断角色名是否有效
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleUploader || role == RoleViewer
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// 用户登录会话（服务端记录，用于查看和吊销其他设备的登录）
+type UserSession struct {
+	Id         int       `json:"id" gorm:"primaryKey"`
+	UserId     int       `json:"user_id" gorm:"index;not null"`
+	Token      string    `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip" gorm:"size:64"`
+	UserAgent  string    `json:"user_agent" gorm:"size:512"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"log"
 	"net/http"
 	"time"
 
@@ -19,6 +20,11 @@ func SetupRoutes() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 
+	// 只有受信任的反向代理转发的 X-Forwarded-For 才用于识别客户端IP
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("TRUSTED_PROXIES 配置无效:", err)
+	}
+
 	// 基础中间件
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
@@ -37,39 +43,141 @@ func SetupRoutes() *gin.Engine {
 
 	// 静态资源
 	r.Static("/static", "./static/frontend")
-	r.Static("/uploads", cfg.UploadPath)
 	r.Static("/assets", "./frontend/dist/assets")
 	r.StaticFile("/favicon.ico", "./frontend/dist/favicon.ico")
 
+	// 上传的图片：回收站中的图片不可访问，私有图片只对有权浏览的登录用户或带有效签名的请求可见
+	// 防盗链检查在签名校验之后，带有效签名的请求不受来源限制
+	uploads := r.Group("/uploads",
+		middlewares.SignedURLMiddleware(),
+		middlewares.HotlinkMiddleware(cfg),
+		middlewares.OptionalAuthMiddleware(),
+	)
+	uploads.GET("/*filepath", controllers.ServeUpload)
+	uploads.HEAD("/*filepath", controllers.ServeUpload)
+
+	// 分享链接（公开访问，可访问私有图片）
+	r.GET("/s/:token", controllers.ServeShare)
+	r.POST("/s/:token", controllers.UnlockShare)
+
 	// API路由分组
 	api := r.Group("/api")
 	{
 		// 公开接口（无需认证）
+		api.GET("/captcha", controllers.GetCaptcha)
 		api.POST("/login", controllers.Login)
+		api.POST("/login/2fa", controllers.LoginTwoFactor)
+		api.GET("/invitations/:code", controllers.GetInvitation)
+		api.POST("/register", controllers.Register)
+		api.GET("/guest/config", controllers.GetGuestConfig)
+		api.POST("/guest/upload", controllers.GuestUpload)
+		api.GET("/guest/images/:token", controllers.GetGuestImage)
+		api.DELETE("/guest/images/:token", controllers.DeleteGuestImage)
+		api.GET("/oidc/config", controllers.GetOIDCConfig)
+		api.GET("/oidc/login", controllers.OIDCLogin)
+		api.GET("/oidc/callback", controllers.OIDCCallback)
 		api.POST("/logout", controllers.Logout)
 		api.GET("/logout", controllers.Logout)
 
-		// 需要认证的接口分组（应用AuthMiddleware）
+		// 需要认证的接口分组（应用AuthMiddleware），每个接口按角色权限校验
 		auth := api.Group("")
 		auth.Use(middlewares.AuthMiddleware())
 		{
+			account := middlewares.PermissionMiddleware(middlewares.PermAccount)
+			imageView := middlewares.PermissionMiddleware(middlewares.PermImageView)
+			imageUpload := middlewares.PermissionMiddleware(middlewares.PermImageUpload)
+			imageManage := middlewares.PermissionMiddleware(middlewares.PermImageManage)
+
 			// 用户信息接口（移到auth分组内）
-			auth.GET("/user/status", controllers.CheckLoginStatus)
+			auth.GET("/user/status", account, controllers.CheckLoginStatus)
+			auth.GET("/user/quota", account, controllers.GetUserQuota)
 
 			// 统计数据
-			auth.GET("/stats/dashboard", controllers.GetDashboardStats)
-			auth.GET("/stats/images", controllers.GetImageStats)
+			auth.GET("/stats/dashboard", imageView, controllers.GetDashboardStats)
+			auth.GET("/stats/images", imageView, controllers.GetImageStats)
 
 			// 图片相关接口
-			auth.POST("/upload", controllers.UploadImage)
-			auth.POST("/upload/images", controllers.UploadImages)
-			auth.DELETE("/images/:id", controllers.DeleteImage)
-			auth.GET("/images", controllers.GetImageList)
-			auth.GET("/images/:id", controllers.GetImageDetail)
+			auth.POST("/upload", imageUpload, controllers.UploadImage)
+			auth.POST("/upload/images", imageUpload, controllers.UploadImages)
+			auth.DELETE("/images/:id", imageManage, controllers.DeleteImage)
+			auth.GET("/images", imageView, controllers.GetImageList)
+			auth.GET("/images/:id", imageView, controllers.GetImageDetail)
+			auth.PATCH("/images/:id", imageManage, controllers.UpdateImageInfo)
+			auth.POST("/images/bulk", imageManage, controllers.BulkImages)
+
+			// 分享链接接口
+			auth.POST("/images/:id/shares", imageManage, controllers.CreateShare)
+			auth.GET("/shares", imageManage, controllers.ListShares)
+			auth.DELETE("/shares/:id", imageManage, controllers.RevokeShare)
+			auth.POST("/images/:id/signed-url", imageManage, controllers.CreateSignedURL)
+
+			// 回收站接口
+			auth.GET("/trash", imageManage, controllers.GetTrashList)
+			auth.DELETE("/trash", imageManage, controllers.EmptyTrash)
+			auth.POST("/trash/:id/restore", imageManage, controllers.RestoreImage)
+			auth.DELETE("/trash/:id", imageManage, controllers.DeleteTrashImage)
+
+			// 相册接口
+			auth.GET("/albums", imageView, controllers.GetAlbumList)
+			auth.POST("/albums", imageManage, controllers.CreateAlbum)
+			auth.GET("/albums/:id", imageView, controllers.GetAlbumDetail)
+			auth.PUT("/albums/:id", imageManage, controllers.UpdateAlbum)
+			auth.DELETE("/albums/:id", imageManage, controllers.DeleteAlbum)
+			auth.POST("/albums/:id/images", imageManage, controllers.AddAlbumImages)
+			auth.DELETE("/albums/:id/images", imageManage, controllers.RemoveAlbumImages)
+			auth.PUT("/albums/:id/images/order", imageManage, controllers.ReorderAlbumImages)
+
+			// 标签接口（标签全站共享，重命名、合并、删除需要管理全部图片的权限）
+			tagManage := middlewares.PermissionMiddleware(middlewares.PermImageManageAll)
+			auth.GET("/tags", imageView, controllers.GetTagSuggestions)
+			auth.POST("/images/:id/tags", imageManage, controllers.AddImageTags)
+			auth.DELETE("/images/:id/tags", imageManage, controllers.RemoveImageTags)
+			auth.POST("/tags/merge", tagManage, controllers.MergeTags)
+			auth.PUT("/tags/:id", tagManage, controllers.RenameTag)
+			auth.DELETE("/tags/:id", tagManage, controllers.DeleteTag)
 
 			// 账户管理接口
-			auth.POST("/account/change", controllers.ChangeAccountInfo)
-			auth.POST("/sessions/clear", controllers.ClearAllSessions)
+			auth.POST("/account/change", account, controllers.ChangeAccountInfo)
+			auth.GET("/sessions", account, controllers.ListSessions)
+			auth.DELETE("/sessions/:id", account, controllers.RevokeSession)
+			auth.POST("/sessions/clear", account, controllers.ClearAllSessions)
+
+			// 两步验证接口
+			auth.GET("/2fa/status", account, controllers.GetTwoFactorStatus)
+			auth.POST("/2fa/setup", account, controllers.SetupTwoFactor)
+			auth.POST("/2fa/enable", account, controllers.EnableTwoFactor)
+			auth.POST("/2fa/disable", account, controllers.DisableTwoFactor)
+			auth.POST("/2fa/recovery-codes", account, controllers.RegenerateRecoveryCodes)
+
+			// 管理员接口
+			admin := auth.Group("/admin")
+			{
+				// 用户管理
+				users := admin.Group("/users", middlewares.PermissionMiddleware(middlewares.PermUserManage))
+				users.GET("", controllers.ListUsers)
+				users.POST("", controllers.CreateUser)
+				users.PUT("/:id/role", controllers.UpdateUserRole)
+				users.PUT("/:id/status", controllers.UpdateUserStatus)
+				users.PUT("/:id/quota", controllers.UpdateUserQuota)
+				users.DELETE("/:id", controllers.DeleteUser)
+
+				// 邀请码管理
+				invitations := admin.Group("/invitations", middlewares.PermissionMiddleware(middlewares.PermUserManage))
+				invitations.GET("", controllers.ListInvitations)
+				invitations.POST("", controllers.CreateInvitation)
+				invitations.DELETE("/:id", controllers.DeleteInvitation)
+
+				// 图片链接管理
+				images := admin.Group("/images", middlewares.PermissionMiddleware(middlewares.PermImageManageAll))
+				images.POST("/rewrite-urls", controllers.RewriteImageURLs)
+
+				// 登录安全管理
+				security := admin.Group("/security", middlewares.PermissionMiddleware(middlewares.PermSecurityManage))
+				security.GET("/lockouts", controllers.GetLoginLocks)
+				security.DELETE("/lockouts", controllers.ClearAllLoginLocks)
+				security.DELETE("/lockouts/:id", controllers.ClearLoginLock)
+				security.GET("/login-attempts", controllers.GetLoginAttempts)
+			}
 		}
 	}
 
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AlbumService 相册服务，负责相册中图片的增删和排序
+// 权限检查由调用方完成
+type AlbumService struct{}
+
+var AlbumSvc *AlbumService
+
+// InitAlbumService 初始化相册服务
+func InitAlbumService() {
+	AlbumSvc = &AlbumService{}
+}
+
+// AddImages 把图片追加到相册末尾，已在相册中的图片保持原位置
+func (s *AlbumService) AddImages(albumID int, imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		return s.addImages(tx, albumID, imageIDs)
+	})
+}
+
+// RemoveImages 从相册中移除图片（不删除图片本身），被移除的封面会被清空
+func (s *AlbumService) RemoveImages(albumID int, imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		return s.removeImages(tx, tx.Where("album_id = ?", albumID), imageIDs)
+	})
+}
+
+// MoveImages 把图片移动到相册：追加到目标相册，并从 from 查询限定的来源相册中移除
+// from 是相册查询，调用方需按权限限定范围，避免影响其他用户的相册
+func (s *AlbumService) MoveImages(from *gorm.DB, albumID int, imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.addImages(tx, albumID, imageIDs); err != nil {
+			return err
+		}
+		source := tx.Where("album_id <> ? AND album_id IN (?)", albumID, from.Select("id"))
+		return s.removeImages(tx, source, imageIDs)
+	})
+}
+
+// addImages 在事务中把图片追加到相册末尾
+func (s *AlbumService) addImages(tx *gorm.DB, albumID int, imageIDs []int) error {
+	var maxPosition int
+	if err := tx.Model(&models.AlbumImage{}).
+		Select("COALESCE(MAX(position), 0)").
+		Where("album_id = ?", albumID).
+		Scan(&maxPosition).Error; err != nil {
+		return fmt.Errorf("failed to read album positions: %v", err)
+	}
+
+	now := time.Now()
+	links := make([]models.AlbumImage, 0, len(imageIDs))
+	for i, imageID := range imageIDs {
+		links = append(links, models.AlbumImage{
+			AlbumId:   albumID,
+			ImageId:   imageID,
+			Position:  maxPosition + i + 1,
+			CreatedAt: now,
+		})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+		return fmt.Errorf("failed to add images to album: %v", err)
+	}
+	return tx.Model(&models.Album{}).Where("id = ?", albumID).Update("updated_at", now).Error
+}
+
+// removeImages 在事务中把图片从 albums 条件限定的相册中移除，并清空被移除的封面
+func (s *AlbumService) removeImages(tx *gorm.DB, albums *gorm.DB, imageIDs []int) error {
+	var albumIDs []int
+	if err := albums.Model(&models.AlbumImage{}).Where("image_id IN ?", imageIDs).
+		Distinct().Pluck("album_id", &albumIDs).Error; err != nil {
+		return fmt.Errorf("failed to read albums: %v", err)
+	}
+	if len(albumIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("album_id IN ? AND image_id IN ?", albumIDs, imageIDs).
+		Delete(&models.AlbumImage{}).Error; err != nil {
+		return fmt.Errorf("failed to remove images from album: %v", err)
+	}
+	return tx.Model(&models.Album{}).
+		Where("id IN ? AND cover_image_id IN ?", albumIDs, imageIDs).
+		Update("cover_image_id", nil).Error
+}
+
+// Reorder 按给定顺序重排相册中的图片，未列出的图片排在后面并保持原有相对顺序
+func (s *AlbumService) Reorder(albumID int, imageIDs []int) error {
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		var current []models.AlbumImage
+		if err := tx.Where("album_id = ?", albumID).Order("position ASC").Find(&current).Error; err != nil {
+			return fmt.Errorf("failed to load album images: %v", err)
+		}
+
+		listed := make(map[int]bool, len(imageIDs))
+		order := make([]int, 0, len(current))
+		inAlbum := make(map[int]bool, len(current))
+		for _, link := range current {
+			inAlbum[link.ImageId] = true
+		}
+		for _, id := range imageIDs {
+			if inAlbum[id] && !listed[id] {
+				listed[id] = true
+				order = append(order, id)
+			}
+		}
+		for _, link := range current {
+			if !listed[link.ImageId] {
+				order = append(order, link.ImageId)
+			}
+		}
+
+		for i, id := range order {
+			if err := tx.Model(&models.AlbumImage{}).
+				Where("album_id = ? AND image_id = ?", albumID, id).
+				Update("position", i+1).Error; err != nil {
+				return fmt.Errorf("failed to update position: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Delete 删除相册及其图片关联，图片本身保留
+func (s *AlbumService) Delete(albumID int) error {
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("album_id = ?", albumID).Delete(&models.AlbumImage{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Album{}, albumID).Error
+	})
+}
+
+// DetachImages 图片被删除时清理其相册关联和封面引用
+func (s *AlbumService) DetachImages(tx *gorm.DB, imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("image_id IN ?", imageIDs).Delete(&models.AlbumImage{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Album{}).Where("cover_image_id IN ?", imageIDs).Update("cover_image_id", nil).Error
+}
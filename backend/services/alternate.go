@@ -0,0 +1,115 @@
+package services
+
+import (
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"oneimg/backend/config"
+	"oneimg/backend/models"
+)
+
+const (
+	alternateWorkers   = 2   // 同时生成备用格式的图片数量
+	alternateQueueSize = 256 // 等待生成的图片数量上限，队列满时丢弃，下次请求时再加入
+)
+
+// AlternateService 在后台生成 WebP 图片的备用格式
+// 解码和重新编码较耗CPU，不在请求中同步进行；生成完成前请求得到原文件
+type AlternateService struct {
+	enabled    bool
+	uploadPath string
+	encoder    []string // AVIF 编码命令，为空时只生成兼容格式
+	queue      chan models.Image
+	mu         sync.Mutex
+	pending    map[int]bool // 已在队列中或正在生成的图片，避免重复加入
+	failed     map[int]bool // 生成失败的图片，本次运行期间不再重试
+}
+
+var AlternateSvc *AlternateService
+
+// InitAlternateService 初始化备用格式生成服务，未开启格式协商时不启动后台任务
+func InitAlternateService(cfg *config.Config) {
+	AlternateSvc = &AlternateService{
+		enabled:    cfg.ContentNegotiation,
+		uploadPath: cfg.UploadPath,
+		queue:      make(chan models.Image, alternateQueueSize),
+		pending:    map[int]bool{},
+		failed:     map[int]bool{},
+	}
+	if !AlternateSvc.enabled {
+		return
+	}
+	if encoder := strings.Fields(cfg.AVIFEncoder); len(encoder) > 0 {
+		if _, err := exec.LookPath(encoder[0]); err != nil {
+			log.Printf("未找到 AVIF 编码器 %s，不生成 AVIF: %v", encoder[0], err)
+		} else {
+			AlternateSvc.encoder = encoder
+		}
+	}
+	for i := 0; i < alternateWorkers; i++ {
+		go AlternateSvc.work()
+	}
+}
+
+// Enqueue 图片缺少备用格式时加入生成队列（上传后和协商格式时调用），不会阻塞
+func (s *AlternateService) Enqueue(image *models.Image) {
+	if !s.enabled || image.MimeType != "image/webp" || !s.missing(image) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[image.Id] || s.failed[image.Id] {
+		return
+	}
+	select {
+	case s.queue <- *image:
+		s.pending[image.Id] = true
+	default:
+	}
+}
+
+// missing 判断图片是否还有未生成的备用格式
+func (s *AlternateService) missing(image *models.Image) bool {
+	return s.missingFallback(image) || s.missingAVIF(image)
+}
+
+func (s *AlternateService) missingFallback(image *models.Image) bool {
+	_, err := ImageSvc.Alternate(s.uploadPath, image, FormatFallback)
+	return err != nil
+}
+
+func (s *AlternateService) missingAVIF(image *models.Image) bool {
+	return len(s.encoder) > 0 && !ImageSvc.HasAVIF(s.uploadPath, image)
+}
+
+func (s *AlternateService) work() {
+	for image := range s.queue {
+		err := s.generate(&image)
+		if err != nil {
+			log.Printf("生成图片 %d 的备用格式失败: %v", image.Id, err)
+		}
+		s.mu.Lock()
+		delete(s.pending, image.Id)
+		if err != nil {
+			s.failed[image.Id] = true
+		}
+		s.mu.Unlock()
+	}
+}
+
+// generate 生成图片缺少的备用格式
+func (s *AlternateService) generate(image *models.Image) error {
+	if s.missingFallback(image) {
+		if _, err := ImageSvc.generateFallback(s.uploadPath, image); err != nil {
+			return err
+		}
+	}
+	if s.missingAVIF(image) {
+		if _, err := ImageSvc.generateAVIF(s.uploadPath, image, s.encoder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
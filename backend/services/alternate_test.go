@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"oneimg/backend/models"
+)
+
+// newTestAlternateImage 在临时上传目录中写入一张不透明的图片
+func newTestAlternateImage(t *testing.T) (string, *models.Image) {
+	t.Helper()
+	uploadPath := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(uploadPath, "2025", "09", "a.webp")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return uploadPath, &models.Image{Id: 1, Url: "/uploads/2025/09/a.webp", MimeType: "image/webp"}
+}
+
+func TestAlternateGenerate(t *testing.T) {
+	uploadPath, image := newTestAlternateImage(t)
+	// cp 代替编码器：把解码后的PNG复制到输出路径
+	svc := &AlternateService{uploadPath: uploadPath, encoder: []string{"cp"}}
+	if !svc.missing(image) {
+		t.Fatal("expected missing alternates before generation")
+	}
+	if err := svc.generate(image); err != nil {
+		t.Fatal(err)
+	}
+	if svc.missing(image) {
+		t.Fatal("expected no missing alternates after generation")
+	}
+
+	alt, err := ImageSvc.Alternate(uploadPath, image, FormatFallback)
+	if err != nil || alt.MimeType != "image/jpeg" {
+		t.Fatalf("expected jpeg fallback, got %+v, %v", alt, err)
+	}
+	alt, err = ImageSvc.Alternate(uploadPath, image, FormatAVIF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(alt.Path)
+	if err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Fatalf("expected encoder output at %s, got err %v", alt.Path, err)
+	}
+
+	// 不应留下临时文件
+	entries, _ := os.ReadDir(filepath.Dir(alt.Path))
+	if len(entries) != 2 {
+		t.Fatalf("expected only the two alternates, got %d files", len(entries))
+	}
+}
+
+func TestAlternateGenerateEncoderFailure(t *testing.T) {
+	uploadPath, image := newTestAlternateImage(t)
+	svc := &AlternateService{uploadPath: uploadPath, encoder: []string{"false"}}
+	if err := svc.generate(image); err == nil {
+		t.Fatal("expected encoder failure")
+	}
+	if ImageSvc.HasAVIF(uploadPath, image) {
+		t.Fatal("failed encoding must not leave an avif file")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(ImageSvc.alternatePath(uploadPath, image, "avif")))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the fallback, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"oneimg/backend/config"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// CaptchaProvider 人机验证提供者
+type CaptchaProvider interface {
+	// Name 提供者名称，前端据此选择验证方式
+	Name() string
+	// Challenge 生成前端完成验证所需的参数
+	Challenge() (*CaptchaChallenge, error)
+	// Verify 校验前端提交的验证token
+	Verify(token, remoteIP string) error
+}
+
+// CaptchaChallenge 下发给前端的验证参数
+type CaptchaChallenge struct {
+	Provider   string `json:"provider"`
+	SiteKey    string `json:"site_key,omitempty"`
+	Endpoint   string `json:"endpoint,omitempty"`
+	Challenge  string `json:"challenge,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+}
+
+var CaptchaSvc CaptchaProvider
+
+// InitCaptchaService 根据配置初始化人机验证服务
+func InitCaptchaService(cfg *config.Config) {
+	switch cfg.CaptchaProvider {
+	case "none", "disabled", "off":
+		CaptchaSvc = &disabledCaptcha{}
+	case "remote_pow":
+		CaptchaSvc = newRemotePowCaptcha(cfg.CaptchaVerifyURL)
+	case "hcaptcha":
+		CaptchaSvc = newSiteVerifyCaptcha("hcaptcha", cfg.CaptchaVerifyURL, "https://api.hcaptcha.com/siteverify", cfg.CaptchaSiteKey, cfg.CaptchaSecret)
+	case "turnstile":
+		CaptchaSvc = newSiteVerifyCaptcha("turnstile", cfg.CaptchaVerifyURL, "https://challenges.cloudflare.com/turnstile/v0/siteverify", cfg.CaptchaSiteKey, cfg.CaptchaSecret)
+	case "siteverify":
+		CaptchaSvc = newSiteVerifyCaptcha("siteverify", cfg.CaptchaVerifyURL, "", cfg.CaptchaSiteKey, cfg.CaptchaSecret)
+	case "pow", "":
+		CaptchaSvc = newPowCaptcha(cfg.CaptchaDifficulty, cfg.SessionSecret)
+	default:
+		log.Printf("未知的人机验证类型 %q，使用内置POW验证", cfg.CaptchaProvider)
+		CaptchaSvc = newPowCaptcha(cfg.CaptchaDifficulty, cfg.SessionSecret)
+	}
+
+	log.Printf("人机验证方式: %s", CaptchaSvc.Name())
+}
+
+// captchaHTTPClient 第三方验证接口使用的HTTP客户端
+var captchaHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// disabledCaptcha 关闭人机验证
+type disabledCaptcha struct{}
+
+func (d *disabledCaptcha) Name() string { return "none" }
+
+func (d *disabledCaptcha) Challenge() (*CaptchaChallenge, error) {
+	return &CaptchaChallenge{Provider: d.Name()}, nil
+}
+
+func (d *disabledCaptcha) Verify(token, remoteIP string) error { return nil }
+
+// powCaptcha 内置工作量证明验证
+// 服务端下发带签名的challenge，客户端寻找nonce使 sha256(challenge + ":" + nonce)
+// 的前导零比特数不少于difficulty，token格式为 "challenge:nonce"
+// challenge格式为 "随机数.过期时间.签名"，签名密钥由 SESSION_SECRET 派生，多实例部署和重启后均可校验；
+// 服务端无需保存已下发的challenge，只在内存中记录已使用过的challenge防止重放，
+// 因此重放保护仅限单个实例，记录数量受工作量证明限制
+type powCaptcha struct {
+	difficulty int
+	ttl        time.Duration
+	key        []byte
+
+	mu        sync.Mutex
+	used      map[string]time.Time
+	nextSweep time.Time
+}
+
+func newPowCaptcha(difficulty int, secret string) *powCaptcha {
+	if difficulty < 1 || difficulty > 32 {
+		difficulty = 18
+	}
+	return &powCaptcha{
+		difficulty: difficulty,
+		ttl:        5 * time.Minute,
+		key:        powSigningKey(secret),
+		used:       make(map[string]time.Time),
+	}
+}
+
+// powSigningKey 由 SESSION_SECRET 派生challenge签名密钥
+// 未修改示例密钥时签名可被伪造，改用进程内随机密钥（重启后已下发的challenge失效）
+func powSigningKey(secret string) []byte {
+	key := make([]byte, 32)
+	if config.IsPlaceholderSecret(secret) {
+		log.Println("SESSION_SECRET 未设置或为示例值，POW验证使用随机密钥，多实例部署时请配置相同的 SESSION_SECRET")
+		if _, err := rand.Read(key); err != nil {
+			log.Fatal("生成POW签名密钥失败:", err)
+		}
+		return key
+	}
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte("oneimg pow captcha")), key); err != nil {
+		log.Fatal("派生POW签名密钥失败:", err)
+	}
+	return key
+}
+
+func (p *powCaptcha) Name() string { return "pow" }
+
+func (p *powCaptcha) Challenge() (*CaptchaChallenge, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %v", err)
+	}
+	expiresAt := time.Now().Add(p.ttl)
+	payload := hex.EncodeToString(buf) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+
+	return &CaptchaChallenge{
+		Provider:   p.Name(),
+		Challenge:  payload + "." + p.sign(payload),
+		Difficulty: p.difficulty,
+		ExpiresAt:  expiresAt.Unix(),
+	}, nil
+}
+
+func (p *powCaptcha) Verify(token, remoteIP string) error {
+	challenge, nonce, ok := strings.Cut(token, ":")
+	if !ok || challenge == "" || nonce == "" {
+		return fmt.Errorf("invalid token")
+	}
+
+	dot := strings.LastIndexByte(challenge, '.')
+	if dot < 0 {
+		return fmt.Errorf("unknown challenge")
+	}
+	payload, signature := challenge[:dot], challenge[dot+1:]
+	if !hmac.Equal([]byte(signature), []byte(p.sign(payload))) {
+		return fmt.Errorf("unknown challenge")
+	}
+	_, expiresStr, _ := strings.Cut(payload, ".")
+	expiresUnix, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return fmt.Errorf("unknown challenge")
+	}
+	expiresAt := time.Unix(expiresUnix, 0)
+	if time.Now().After(expiresAt) {
+		return fmt.Errorf("challenge expired")
+	}
+
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	if leadingZeroBits(sum[:]) < p.difficulty {
+		return fmt.Errorf("insufficient proof of work")
+	}
+
+	// challenge只能使用一次
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.used[challenge]; exists {
+		return fmt.Errorf("challenge already used")
+	}
+	p.sweepLocked()
+	p.used[challenge] = expiresAt
+	return nil
+}
+
+// sign 计算challenge载荷的签名
+func (p *powCaptcha) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sweepLocked 清理已过期的使用记录，每分钟最多执行一次，调用方需持有锁
+func (p *powCaptcha) sweepLocked() {
+	now := time.Now()
+	if now.Before(p.nextSweep) {
+		return
+	}
+	p.nextSweep = now.Add(time.Minute)
+	for challenge, expiresAt := range p.used {
+		if now.After(expiresAt) {
+			delete(p.used, challenge)
+		}
+	}
+}
+
+// leadingZeroBits 计算字节序列的前导零比特数
+func leadingZeroBits(data []byte) int {
+	count := 0
+	for _, b := range data {
+		if b == 0 {
+			count += 8
+			continue
+		}
+		count += bits.LeadingZeros8(b)
+		break
+	}
+	return count
+}
+
+// remotePowCaptcha 远程POW验证服务（cha.eta.im 兼容接口）
+type remotePowCaptcha struct {
+	verifyURL string
+}
+
+func newRemotePowCaptcha(verifyURL string) *remotePowCaptcha {
+	if verifyURL == "" {
+		verifyURL = "https://cha.eta.im/api/validate"
+	}
+	return &remotePowCaptcha{verifyURL: verifyURL}
+}
+
+func (r *remotePowCaptcha) Name() string { return "remote_pow" }
+
+func (r *remotePowCaptcha) Challenge() (*CaptchaChallenge, error) {
+	endpoint := r.verifyURL
+	if u, err := url.Parse(r.verifyURL); err == nil {
+		endpoint = u.Scheme + "://" + u.Host + "/"
+	}
+	return &CaptchaChallenge{Provider: r.Name(), Endpoint: endpoint}, nil
+}
+
+func (r *remotePowCaptcha) Verify(token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("missing token")
+	}
+
+	body, err := json.Marshal(map[string]string{"token": token})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", r.verifyURL, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+
+	resp, err := captchaHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("verify request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return fmt.Errorf("invalid verify response: %v", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("token rejected")
+	}
+	return nil
+}
+
+// siteVerifyCaptcha hCaptcha / Turnstile 风格的 siteverify 接口
+type siteVerifyCaptcha struct {
+	name      string
+	verifyURL string
+	siteKey   string
+	secret    string
+}
+
+func newSiteVerifyCaptcha(name, verifyURL, defaultURL, siteKey, secret string) *siteVerifyCaptcha {
+	if verifyURL == "" {
+		verifyURL = defaultURL
+	}
+	if verifyURL == "" || secret == "" {
+		log.Printf("人机验证 %s 缺少 CAPTCHA_VERIFY_URL 或 CAPTCHA_SECRET 配置，所有验证都将失败", name)
+	}
+	return &siteVerifyCaptcha{
+		name:      name,
+		verifyURL: verifyURL,
+		siteKey:   siteKey,
+		secret:    secret,
+	}
+}
+
+func (s *siteVerifyCaptcha) Name() string { return s.name }
+
+func (s *siteVerifyCaptcha) Challenge() (*CaptchaChallenge, error) {
+	return &CaptchaChallenge{Provider: s.name, SiteKey: s.siteKey}, nil
+}
+
+func (s *siteVerifyCaptcha) Verify(token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("missing token")
+	}
+	if s.verifyURL == "" || s.secret == "" {
+		return fmt.Errorf("captcha is not configured")
+	}
+
+	form := url.Values{}
+	form.Set("secret", s.secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	if s.siteKey != "" {
+		form.Set("sitekey", s.siteKey)
+	}
+
+	resp, err := captchaHTTPClient.PostForm(s.verifyURL, form)
+	if err != nil {
+		return fmt.Errorf("verify request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return fmt.Errorf("invalid verify response: %v", err)
+	}
+	if !result.Success {
+		if len(result.ErrorCodes) > 0 {
+			return fmt.Errorf("token rejected: %s", strings.Join(result.ErrorCodes, ","))
+		}
+		return fmt.Errorf("token rejected")
+	}
+	return nil
+}
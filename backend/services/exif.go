@@ -0,0 +1,51 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ExifInfo 从原图中提取的拍摄信息（图片转换为WebP后EXIF会丢失，因此在上传时保存）
+type ExifInfo struct {
+	CameraMake  string
+	CameraModel string
+	LensModel   string
+	TakenAt     *time.Time
+}
+
+// readExif 读取EXIF信息，图片不含EXIF时返回空值
+func readExif(data []byte) ExifInfo {
+	var info ExifInfo
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return info
+	}
+
+	info.CameraMake = exifString(x, exif.Make)
+	info.CameraModel = exifString(x, exif.Model)
+	info.LensModel = exifString(x, exif.LensModel)
+	if taken, err := x.DateTime(); err == nil && !taken.IsZero() {
+		info.TakenAt = &taken
+	}
+	return info
+}
+
+// exifString 读取字符串类型的EXIF字段（最长100个字符）
+func exifString(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil {
+		return ""
+	}
+	value, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	value = strings.TrimSpace(strings.Trim(value, "\x00"))
+	if runes := []rune(value); len(runes) > 100 {
+		value = string(runes[:100])
+	}
+	return value
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+)
+
+// 过期图片的清理间隔
+const guestPurgeInterval = 10 * time.Minute
+
+// GuestLimitError 游客上传超出限制
+type GuestLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *GuestLimitError) Error() string {
+	return e.Message
+}
+
+// GuestService 游客匿名上传服务
+// 按IP限制每小时上传次数和每天上传字节数，定期删除过期的游客图片
+type GuestService struct {
+	maxFileSize int64
+	perHour     int
+	bytesPerDay int64
+	imageTTL    time.Duration
+	mu          sync.Mutex // 串行化限流检查和预占记录，避免并发绕过
+}
+
+var GuestSvc *GuestService
+
+// InitGuestService 初始化游客上传服务，未启用时不创建
+func InitGuestService(cfg *config.Config) {
+	if !cfg.GuestUploadEnabled {
+		return
+	}
+	GuestSvc = &GuestService{
+		maxFileSize: cfg.GuestMaxFileSize,
+		perHour:     cfg.GuestUploadsPerHour,
+		bytesPerDay: cfg.GuestBytesPerDay,
+		imageTTL:    cfg.GuestImageTTL,
+	}
+
+	go func() {
+		GuestSvc.PurgeExpired()
+		ticker := time.NewTicker(guestPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			GuestSvc.PurgeExpired()
+		}
+	}()
+	log.Println("已启用游客匿名上传")
+}
+
+// Enabled 是否启用了游客上传
+func (s *GuestService) Enabled() bool {
+	return s != nil
+}
+
+// MaxFileSize 游客单个文件大小上限
+func (s *GuestService) MaxFileSize() int64 {
+	return s.maxFileSize
+}
+
+// Reserve 检查IP是否还能上传 size 字节的文件，可以上传时立即写入上传记录预占限额
+// 检查和写入在锁内完成，耗时的图片处理在锁外进行；处理失败时需调用 Release 释放
+func (s *GuestService) Reserve(ip string, size int64) (*models.GuestUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.check(ip, size); err != nil {
+		return nil, err
+	}
+	record := &models.GuestUpload{IP: ip, FileSize: size, CreatedAt: time.Now()}
+	if err := database.GetDB().DB.Create(record).Error; err != nil {
+		return nil, fmt.Errorf("failed to record guest upload: %v", err)
+	}
+	return record, nil
+}
+
+// Release 上传失败时释放预占的限额
+func (s *GuestService) Release(record *models.GuestUpload) {
+	if err := database.GetDB().DB.Delete(record).Error; err != nil {
+		log.Printf("释放游客上传记录失败: %v", err)
+	}
+}
+
+// check 检查IP是否还能上传 size 字节的文件
+func (s *GuestService) check(ip string, size int64) error {
+	db := database.GetDB().DB
+	now := time.Now()
+
+	if s.perHour > 0 {
+		var records []models.GuestUpload
+		if err := db.Where("ip = ? AND created_at > ?", ip, now.Add(-time.Hour)).
+			Order("created_at ASC").Find(&records).Error; err != nil {
+			return fmt.Errorf("failed to count guest uploads: %v", err)
+		}
+		if len(records) >= s.perHour {
+			return &GuestLimitError{
+				Message:    fmt.Sprintf("上传过于频繁，每小时最多上传%d张", s.perHour),
+				RetryAfter: records[0].CreatedAt.Add(time.Hour).Sub(now),
+			}
+		}
+	}
+
+	if s.bytesPerDay > 0 {
+		var used int64
+		if err := db.Model(&models.GuestUpload{}).
+			Select("COALESCE(SUM(file_size), 0)").
+			Where("ip = ? AND created_at > ?", ip, now.Add(-24*time.Hour)).
+			Scan(&used).Error; err != nil {
+			return fmt.Errorf("failed to sum guest uploads: %v", err)
+		}
+		if used+size > s.bytesPerDay {
+			return &GuestLimitError{
+				Message:    fmt.Sprintf("今日上传总量已达上限（%s）", formatBytes(s.bytesPerDay)),
+				RetryAfter: 24 * time.Hour,
+			}
+		}
+	}
+	return nil
+}
+
+// ExpiresAt 新上传的游客图片的过期时间，永久保留时为nil
+func (s *GuestService) ExpiresAt() *time.Time {
+	if s.imageTTL <= 0 {
+		return nil
+	}
+	t := time.Now().Add(s.imageTTL)
+	return &t
+}
+
+// NewDeleteToken 生成删除链接密钥
+func (s *GuestService) NewDeleteToken() (string, error) {
+	return randomHex(24)
+}
+
+// PurgeExpired 删除已过期的图片及其文件，并清理过期的上传记录
+func (s *GuestService) PurgeExpired() {
+	db := database.GetDB().DB
+	now := time.Now()
+
+	// 过期的游客图片直接彻底删除（包括已在回收站中的）
+	var images []models.Image
+	if err := db.Unscoped().Where("expires_at IS NOT NULL AND expires_at <= ?", now).Find(&images).Error; err != nil {
+		log.Printf("查询过期图片失败: %v", err)
+		return
+	}
+	if destroyed, _ := TrashSvc.DestroyAll(images); destroyed > 0 {
+		log.Printf("已清理%d张过期的游客图片", destroyed)
+	}
+
+	db.Where("created_at <= ?", now.Add(-24*time.Hour)).Delete(&models.GuestUpload{})
+}
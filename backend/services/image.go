@@ -2,6 +2,8 @@ package services
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"image"
 	"image/gif"
@@ -9,11 +11,17 @@ import (
 	"image/png"
 	"io"
 	"mime/multipart"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
 	"github.com/chai2010/webp"
 	"github.com/disintegration/imaging"
+	"gorm.io/gorm"
 )
 
 type ImageService struct{}
@@ -25,6 +33,63 @@ func InitImageService() {
 	ImageSvc = &ImageService{}
 }
 
+// FilePath 根据图片URL得到磁盘上的文件路径
+func (s *ImageService) FilePath(uploadPath string, image *models.Image) string {
+	// 从URL中提取文件路径
+	// URL格式: /uploads/2025/09/filename.ext
+	// 需要去掉前缀 "/uploads/" 得到相对路径
+	relativePath := strings.TrimPrefix(image.Url, "/uploads/")
+
+	// 构建完整文件路径
+	return filepath.Join(uploadPath, relativePath)
+}
+
+// RemoveFile 删除图片对应的物理文件及其备用格式，文件已不存在时不视为错误
+func (s *ImageService) RemoveFile(uploadPath string, image *models.Image) error {
+	if err := os.Remove(s.FilePath(uploadPath, image)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %v", err)
+	}
+	s.removeAlternates(uploadPath, image)
+	return nil
+}
+
+// ContentHash 计算文件内容的SHA-256，用作ETag
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// EnsureContentHash 返回图片的内容哈希，旧版本上传的图片没有哈希时读取文件计算并保存
+func (s *ImageService) EnsureContentHash(uploadPath string, image *models.Image) (string, error) {
+	if image.ContentHash != "" {
+		return image.ContentHash, nil
+	}
+	data, err := os.ReadFile(s.FilePath(uploadPath, image))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	image.ContentHash = ContentHash(data)
+	if err := database.GetDB().DB.Model(&models.Image{}).Where("id = ?", image.Id).
+		Update("content_hash", image.ContentHash).Error; err != nil {
+		return "", fmt.Errorf("failed to save content hash: %v", err)
+	}
+	return image.ContentHash, nil
+}
+
+// DetachRelations 图片被永久删除前清理其相册、标签关联、分享链接和搜索索引
+func (s *ImageService) DetachRelations(tx *gorm.DB, imageIDs []int) error {
+	if err := AlbumSvc.DetachImages(tx, imageIDs); err != nil {
+		return err
+	}
+	if err := TagSvc.DetachImages(tx, imageIDs); err != nil {
+		return err
+	}
+	if err := ShareSvc.DetachImages(tx, imageIDs); err != nil {
+		return err
+	}
+	return SearchSvc.Remove(tx, imageIDs)
+}
+
 // ProcessImage 处理图片（压缩、获取尺寸等）
 func (s *ImageService) ProcessImage(file multipart.File, header *multipart.FileHeader) (*ProcessedImage, error) {
 	// 读取文件内容
@@ -104,6 +169,7 @@ func (s *ImageService) ProcessImage(file multipart.File, header *multipart.FileH
 		Height:          height,
 		Format:          finalFormat,
 		MimeType:        finalMimeType,
+		Exif:            readExif(fileBytes),
 	}, nil
 }
 
@@ -227,4 +293,5 @@ type ProcessedImage struct {
 	Height          int
 	Format          string
 	MimeType        string
+	Exif            ExifInfo
 }
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvitationInvalid = errors.New("invitation is invalid or used up")
+	ErrUsernameTaken     = errors.New("username already exists")
+)
+
+// InvitationService 邀请注册服务
+type InvitationService struct{}
+
+var InvitationSvc *InvitationService
+
+// InitInvitationService 初始化邀请注册服务
+func InitInvitationService() {
+	InvitationSvc = &InvitationService{}
+}
+
+// Create 生成新的邀请码
+func (s *InvitationService) Create(createdBy int, role string, maxUses int, expiresAt *time.Time, note string) (*models.Invitation, error) {
+	code, err := randomHex(12)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.Invitation{
+		Code:      code,
+		Role:      role,
+		MaxUses:   maxUses,
+		Note:      note,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := database.GetDB().DB.Create(invitation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %v", err)
+	}
+	return invitation, nil
+}
+
+// Find 查找仍可使用的邀请码
+func (s *InvitationService) Find(code string) (*models.Invitation, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, ErrInvitationInvalid
+	}
+
+	var invitation models.Invitation
+	if err := database.GetDB().DB.Where("code = ?", code).First(&invitation).Error; err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	if !invitation.IsUsable(time.Now()) {
+		return nil, ErrInvitationInvalid
+	}
+	return &invitation, nil
+}
+
+// Redeem 使用邀请码注册用户，占用名额和创建用户在同一事务中完成
+func (s *InvitationService) Redeem(code, username, passwordHash string) (*models.User, error) {
+	invitation, err := s.Find(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发注册时不会超出使用次数
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND (max_uses = 0 OR uses < max_uses) AND (expires_at IS NULL OR expires_at > ?)", invitation.Id, time.Now()).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationInvalid
+		}
+
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUsernameTaken
+		}
+
+		user = models.User{
+			Username: username,
+			Password: passwordHash,
+			Role:     invitation.Role,
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 存储驱动（目前图片都保存在本地上传目录）
+const StorageLocal = "local"
+
+// LinkService 生成对外的绝对链接
+// 站点地址和图片地址分别配置（图片可走CDN或独立域名），未配置时使用请求的地址
+type LinkService struct {
+	siteBaseURL   string
+	imageBaseURLs map[string]string
+}
+
+var LinkSvc *LinkService
+
+// InitLinkService 初始化链接服务
+func InitLinkService(cfg *config.Config) {
+	LinkSvc = &LinkService{
+		siteBaseURL:   cfg.PublicBaseURL,
+		imageBaseURLs: cfg.ImageBaseURLs,
+	}
+}
+
+// SiteURL 站点页面的绝对链接，requestBase 为根据请求推断的站点地址
+func (s *LinkService) SiteURL(requestBase, path string) string {
+	if s.siteBaseURL != "" {
+		return s.siteBaseURL + path
+	}
+	return requestBase + path
+}
+
+// ImageBase 图片链接使用的地址，未配置时返回空字符串
+func (s *LinkService) ImageBase() string {
+	return s.imageBaseURLs[StorageLocal]
+}
+
+// ImageURL 图片的绝对链接
+func (s *LinkService) ImageURL(requestBase string, image *models.Image) string {
+	if base := s.ImageBase(); base != "" {
+		return base + image.Url
+	}
+	return requestBase + image.Url
+}
+
+// StoredURL 上传时保存的绝对链接，只使用配置的图片地址；未配置时返回空字符串，由 Fill 在响应时生成
+func (s *LinkService) StoredURL(image *models.Image) string {
+	if base := s.ImageBase(); base != "" {
+		return base + image.Url
+	}
+	return ""
+}
+
+// Fill 为没有保存绝对链接的图片（旧版本上传或未配置图片地址）填充 PublicUrl，不写入数据库
+func (s *LinkService) Fill(requestBase string, images []models.Image) {
+	for i := range images {
+		if images[i].PublicUrl == "" {
+			images[i].PublicUrl = s.ImageURL(requestBase, &images[i])
+		}
+	}
+}
+
+// Rewrite 按新的图片地址重写所有图片（包括回收站中的图片）保存的绝对链接，返回更新的数量
+// 按ID分批，每批用一条 UPDATE 拼接新地址和相对路径
+func (s *LinkService) Rewrite(base string) (int, error) {
+	db := database.GetDB().DB
+	concat := gorm.Expr("? || url", base)
+	if db.Dialector.Name() == "mysql" {
+		concat = gorm.Expr("CONCAT(?, url)", base)
+	}
+
+	updated, lastID := 0, 0
+	for {
+		var ids []int
+		if err := db.Unscoped().Model(&models.Image{}).Where("id > ?", lastID).
+			Order("id ASC").Limit(500).Pluck("id", &ids).Error; err != nil {
+			return updated, fmt.Errorf("failed to load images: %v", err)
+		}
+		if len(ids) == 0 {
+			return updated, nil
+		}
+
+		maxID := ids[len(ids)-1]
+		if err := db.Unscoped().Model(&models.Image{}).Where("id > ? AND id <= ?", lastID, maxID).
+			Update("public_url", concat).Error; err != nil {
+			return updated, fmt.Errorf("failed to rewrite images %d-%d: %v", ids[0], maxID, err)
+		}
+		updated += len(ids)
+		lastID = maxID
+	}
+}
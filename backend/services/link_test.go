@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"oneimg/backend/models"
+)
+
+func TestLinkRewrite(t *testing.T) {
+	db := initTestDB(t)
+	// 超过一批的数量，包含回收站中的图片
+	images := make([]models.Image, 501)
+	for i := range images {
+		images[i] = models.Image{
+			Url:       fmt.Sprintf("/uploads/2025/09/%d.webp", i),
+			PublicUrl: fmt.Sprintf("https://old.example.com/uploads/2025/09/%d.webp", i),
+			CreatedAt: time.Now(),
+		}
+	}
+	if err := db.CreateInBatches(&images, 100).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&images[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &LinkService{}
+	updated, err := svc.Rewrite("https://cdn.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != len(images) {
+		t.Fatalf("expected %d images updated, got %d", len(images), updated)
+	}
+
+	var stale int64
+	db.Unscoped().Model(&models.Image{}).Where("public_url NOT LIKE ?", "https://cdn.example.com/uploads/%").Count(&stale)
+	if stale != 0 {
+		t.Fatalf("expected all links rewritten, %d left", stale)
+	}
+	var trashed models.Image
+	db.Unscoped().First(&trashed, images[0].Id)
+	if trashed.PublicUrl != "https://cdn.example.com/uploads/2025/09/0.webp" {
+		t.Fatalf("unexpected link for trashed image: %s", trashed.PublicUrl)
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	LockKindIP   = "ip"
+	LockKindUser = "user"
+)
+
+// LoginGuard 登录防爆破服务
+// 按IP和用户名分别统计连续失败次数：每次失败后按指数退避延迟下一次尝试，
+// IP达到阈值后锁定一段时间；用户名只退避不锁定，避免任何人都能让指定账号（如 admin）无法登录；
+// 同时记录每次登录尝试用于审计
+type LoginGuard struct {
+	maxIPFailures  int
+	lockDuration   time.Duration
+	failureWindow  time.Duration
+	backoffBase    time.Duration
+	backoffMax     time.Duration
+	auditRetention time.Duration
+}
+
+// loginGuardPurgeInterval 清理过期记录的间隔
+const loginGuardPurgeInterval = time.Hour
+
+var LoginGuardSvc *LoginGuard
+
+// InitLoginGuard 初始化登录防爆破服务
+func InitLoginGuard(cfg *config.Config) {
+	LoginGuardSvc = &LoginGuard{
+		maxIPFailures:  cfg.LoginMaxIPFailures,
+		lockDuration:   cfg.LoginLockDuration,
+		failureWindow:  cfg.LoginFailureWindow,
+		backoffBase:    cfg.LoginBackoffBase,
+		backoffMax:     cfg.LoginBackoffMax,
+		auditRetention: cfg.LoginAuditRetention,
+	}
+
+	go func() {
+		LoginGuardSvc.PurgeExpired()
+		ticker := time.NewTicker(loginGuardPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			LoginGuardSvc.PurgeExpired()
+		}
+	}()
+}
+
+// normalizeUsername 统一用户名大小写，避免通过大小写变化绕过计数
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Check 检查IP和用户名是否处于退避或锁定期，返回还需等待的时间
+func (g *LoginGuard) Check(ip, username string) time.Duration {
+	db := database.GetDB().DB
+	now := time.Now()
+
+	var locks []models.LoginLock
+	db.Where("(kind = ? AND value = ?) OR (kind = ? AND value = ?)",
+		LockKindIP, ip, LockKindUser, normalizeUsername(username)).
+		Find(&locks)
+
+	var wait time.Duration
+	for _, lock := range locks {
+		if remaining := lock.LockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// RecordFailure 记录一次失败的登录
+func (g *LoginGuard) RecordFailure(ip, username, userAgent, reason string) {
+	g.audit(ip, username, userAgent, false, reason)
+	g.bump(LockKindIP, ip, g.maxIPFailures)
+	g.bump(LockKindUser, normalizeUsername(username), 0)
+}
+
+// RecordIPFailure 记录一次只计入IP的失败（如人机验证未通过），不影响该用户名
+func (g *LoginGuard) RecordIPFailure(ip, username, userAgent, reason string) {
+	g.audit(ip, username, userAgent, false, reason)
+	g.bump(LockKindIP, ip, g.maxIPFailures)
+}
+
+// RecordSuccess 记录一次成功的登录，并清除该用户名的失败计数
+func (g *LoginGuard) RecordSuccess(ip, username, userAgent string) {
+	g.audit(ip, username, userAgent, true, "")
+	database.GetDB().DB.
+		Where("kind = ? AND value = ?", LockKindUser, normalizeUsername(username)).
+		Delete(&models.LoginLock{})
+}
+
+// bump 增加失败计数并计算下一次允许尝试的时间，maxFailures 为0时只退避不锁定
+func (g *LoginGuard) bump(kind, value string, maxFailures int) {
+	if value == "" {
+		return
+	}
+
+	db := database.GetDB().DB
+	now := time.Now()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var lock models.LoginLock
+		err := tx.Where("kind = ? AND value = ?", kind, value).First(&lock).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// 超出统计窗口则重新计数
+		if lock.Id == 0 || now.Sub(lock.LastFailureAt) > g.failureWindow {
+			lock.Failures = 0
+		}
+		lock.Kind = kind
+		lock.Value = value
+		lock.Failures++
+		lock.LastFailureAt = now
+
+		if maxFailures > 0 && lock.Failures >= maxFailures {
+			lock.LockedUntil = now.Add(g.lockDuration)
+		} else {
+			lock.LockedUntil = now.Add(g.backoff(lock.Failures))
+		}
+
+		return tx.Save(&lock).Error
+	})
+	if err != nil {
+		log.Printf("记录登录失败次数失败: %v", err)
+	}
+}
+
+// backoff 第n次失败后的退避时间：base * 2^(n-1)，不超过max
+func (g *LoginGuard) backoff(failures int) time.Duration {
+	if g.backoffBase <= 0 || failures < 1 {
+		return 0
+	}
+	delay := g.backoffBase
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if g.backoffMax > 0 && delay >= g.backoffMax {
+			return g.backoffMax
+		}
+	}
+	return delay
+}
+
+// audit 写入登录审计记录
+func (g *LoginGuard) audit(ip, username, userAgent string, success bool, reason string) {
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	attempt := models.LoginAttempt{
+		Username:  username,
+		IP:        ip,
+		UserAgent: userAgent,
+		Success:   success,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if err := database.GetDB().DB.Create(&attempt).Error; err != nil {
+		log.Printf("写入登录审计记录失败: %v", err)
+	}
+}
+
+// ListLocks 列出仍处于锁定或退避期的记录
+func (g *LoginGuard) ListLocks() ([]models.LoginLock, error) {
+	var locks []models.LoginLock
+	err := database.GetDB().DB.
+		Where("locked_until > ?", time.Now()).
+		Order("locked_until DESC").
+		Find(&locks).Error
+	return locks, err
+}
+
+// ClearLock 清除指定的锁定记录
+func (g *LoginGuard) ClearLock(id int) (bool, error) {
+	result := database.GetDB().DB.Delete(&models.LoginLock{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ClearAllLocks 清除全部锁定记录
+func (g *LoginGuard) ClearAllLocks() error {
+	return database.GetDB().DB.Where("1 = 1").Delete(&models.LoginLock{}).Error
+}
+
+// PurgeExpired 清理已失效的锁定记录和超过保留期的审计记录
+// 锁定期已过且超出统计窗口的记录不再影响计数，可以安全删除
+func (g *LoginGuard) PurgeExpired() {
+	db := database.GetDB().DB
+	now := time.Now()
+
+	if err := db.Where("locked_until <= ? AND last_failure_at <= ?", now, now.Add(-g.failureWindow)).
+		Delete(&models.LoginLock{}).Error; err != nil {
+		log.Printf("清理登录锁定记录失败: %v", err)
+	}
+	if err := db.Where("created_at <= ?", now.Add(-g.auditRetention)).
+		Delete(&models.LoginAttempt{}).Error; err != nil {
+		log.Printf("清理登录审计记录失败: %v", err)
+	}
+}
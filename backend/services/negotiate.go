@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"oneimg/backend/models"
+)
+
+// 可协商的输出格式
+const (
+	FormatOriginal = "original" // 上传后保存的文件
+	FormatAVIF     = "avif"     // 只在已生成 AVIF 备用文件时提供
+	FormatWebP     = "webp"
+	FormatFallback = "fallback" // 兼容格式：JPEG，有透明通道时为PNG
+)
+
+// 备用格式文件的存放目录（位于上传目录下，不通过 /uploads 直接访问）
+const alternatesDir = ".alternates"
+
+// 单张图片 AVIF 编码的最长时间
+const avifEncodeTimeout = 2 * time.Minute
+
+var ErrAlternateUnavailable = errors.New("alternate format unavailable")
+
+// Alternate 图片的一种备用格式
+type Alternate struct {
+	Path     string
+	MimeType string
+	Variant  string // 用于区分ETag，如 jpeg、png、avif
+}
+
+// NegotiateFormat 根据 Accept 请求头选择输出格式（仅用于以 WebP 保存的图片）
+// WebP 和 AVIF 需要客户端明确声明支持，只接受通配类型的客户端（老旧邮件客户端、部分爬虫）得到兼容格式
+// 没有 Accept 请求头时返回原文件
+func NegotiateFormat(accept string, hasAVIF bool) string {
+	if strings.TrimSpace(accept) == "" {
+		return FormatOriginal
+	}
+
+	quality := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		quality[mediaType] = max(quality[mediaType], q)
+	}
+
+	// 按服务端偏好排列，q值相同时取靠前的格式
+	best, bestQ := FormatFallback, 0.0
+	for _, candidate := range []struct {
+		format string
+		q      float64
+	}{
+		{FormatAVIF, quality["image/avif"]},
+		{FormatWebP, quality["image/webp"]},
+		{FormatFallback, max(quality["image/jpeg"], quality["image/png"], quality["image/*"], quality["*/*"])},
+	} {
+		if candidate.format == FormatAVIF && !hasAVIF {
+			continue
+		}
+		if candidate.q > bestQ {
+			best, bestQ = candidate.format, candidate.q
+		}
+	}
+	return best
+}
+
+// alternatePath 备用格式文件路径，与原文件保持相同的目录结构
+func (s *ImageService) alternatePath(uploadPath string, image *models.Image, ext string) string {
+	relativePath := strings.TrimPrefix(image.Url, "/uploads/")
+	return filepath.Join(uploadPath, alternatesDir, relativePath+"."+ext)
+}
+
+// HasAVIF 判断图片是否已生成 AVIF 备用文件
+func (s *ImageService) HasAVIF(uploadPath string, image *models.Image) bool {
+	_, err := os.Stat(s.alternatePath(uploadPath, image, "avif"))
+	return err == nil
+}
+
+// Alternate 返回图片已生成的备用格式文件，尚未生成时返回 ErrAlternateUnavailable
+func (s *ImageService) Alternate(uploadPath string, image *models.Image, format string) (*Alternate, error) {
+	switch format {
+	case FormatAVIF:
+		path := s.alternatePath(uploadPath, image, "avif")
+		if _, err := os.Stat(path); err != nil {
+			return nil, ErrAlternateUnavailable
+		}
+		return &Alternate{Path: path, MimeType: "image/avif", Variant: "avif"}, nil
+	case FormatFallback:
+		for _, alt := range []Alternate{
+			{Path: s.alternatePath(uploadPath, image, "jpg"), MimeType: "image/jpeg", Variant: "jpeg"},
+			{Path: s.alternatePath(uploadPath, image, "png"), MimeType: "image/png", Variant: "png"},
+		} {
+			if _, err := os.Stat(alt.Path); err == nil {
+				return &alt, nil
+			}
+		}
+	}
+	return nil, ErrAlternateUnavailable
+}
+
+// generateFallback 由原文件生成兼容格式：不透明的图片生成JPEG，有透明通道的生成PNG
+func (s *ImageService) generateFallback(uploadPath string, image *models.Image) (*Alternate, error) {
+	data, err := os.ReadFile(s.FilePath(uploadPath, image))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	img, _, err := s.decodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	alt := Alternate{Path: s.alternatePath(uploadPath, image, "jpg"), MimeType: "image/jpeg", Variant: "jpeg"}
+	var buf bytes.Buffer
+	if isOpaque(img) {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		alt = Alternate{Path: s.alternatePath(uploadPath, image, "png"), MimeType: "image/png", Variant: "png"}
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %v", alt.Variant, err)
+	}
+
+	if err := writeAlternate(alt.Path, func(tmp *os.File) error {
+		_, err := tmp.Write(buf.Bytes())
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return &alt, nil
+}
+
+// generateAVIF 用外部编码器由原文件生成 AVIF：先解码为临时PNG，再交给编码器
+// encoder 为命令及参数，末尾追加输入和输出文件路径
+func (s *ImageService) generateAVIF(uploadPath string, image *models.Image, encoder []string) (*Alternate, error) {
+	data, err := os.ReadFile(s.FilePath(uploadPath, image))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	img, _, err := s.decodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	input, err := os.CreateTemp("", "oneimg-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(input.Name())
+	err = png.Encode(input, img)
+	if closeErr := input.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode png: %v", err)
+	}
+
+	alt := Alternate{Path: s.alternatePath(uploadPath, image, "avif"), MimeType: "image/avif", Variant: "avif"}
+	if err := writeAlternate(alt.Path, func(tmp *os.File) error {
+		tmp.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), avifEncodeTimeout)
+		defer cancel()
+		args := append(encoder[1:len(encoder):len(encoder)], input.Name(), tmp.Name())
+		if output, err := exec.CommandContext(ctx, encoder[0], args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%v: %s", err, bytes.TrimSpace(output))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &alt, nil
+}
+
+// writeAlternate 写入临时文件再重命名，请求不会读到不完整的文件
+// 临时文件保留原扩展名，外部编码器可按扩展名识别输出格式
+func writeAlternate(path string, write func(tmp *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil && !errors.Is(closeErr, os.ErrClosed) {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write alternate: %v", err)
+	}
+	return nil
+}
+
+// removeAlternates 删除图片的所有备用格式文件
+func (s *ImageService) removeAlternates(uploadPath string, image *models.Image) {
+	for _, ext := range []string{"avif", "jpg", "png"} {
+		os.Remove(s.alternatePath(uploadPath, image, ext))
+	}
+}
+
+// isOpaque 判断图片是否不含透明像素
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
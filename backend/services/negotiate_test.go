@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestNegotiateFormat(t *testing.T) {
+	chrome := "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
+	tests := []struct {
+		name    string
+		accept  string
+		hasAVIF bool
+		want    string
+	}{
+		{"no accept header", "", true, FormatOriginal},
+		{"blank accept header", "   ", true, FormatOriginal},
+		{"browser with avif available", chrome, true, FormatAVIF},
+		{"browser without avif file", chrome, false, FormatWebP},
+		{"webp only", "image/webp,*/*;q=0.8", true, FormatWebP},
+		{"wildcard only", "*/*", true, FormatFallback},
+		{"image wildcard only", "image/*", true, FormatFallback},
+		{"jpeg only", "image/jpeg", false, FormatFallback},
+		{"png only", "image/png", false, FormatFallback},
+		{"webp refused with q=0", "image/webp;q=0,*/*", false, FormatFallback},
+		{"avif refused with q=0", "image/avif;q=0,image/webp", true, FormatWebP},
+		{"higher q wins", "image/webp;q=0.5,image/jpeg;q=0.9", false, FormatFallback},
+		{"tie prefers avif", "image/avif;q=0.9,image/webp;q=0.9", true, FormatAVIF},
+		{"tie prefers webp over fallback", "image/webp;q=0.8,*/*;q=0.8", false, FormatWebP},
+		{"media types are case insensitive", "Image/WebP", false, FormatWebP},
+		{"spaces and extra params", " image/webp ; level=1 ; q=0.7 , image/jpeg;q=0.6", false, FormatWebP},
+		{"invalid q treated as 1", "image/webp;q=abc,image/jpeg;q=0.9", false, FormatWebP},
+		{"duplicate entries use highest q", "image/webp;q=0.1,image/webp;q=0.9,image/jpeg;q=0.5", false, FormatWebP},
+		{"unrelated types only", "text/html", true, FormatFallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NegotiateFormat(tt.accept, tt.hasAVIF); got != tt.want {
+				t.Errorf("NegotiateFormat(%q, %v) = %q, want %q", tt.accept, tt.hasAVIF, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// 登录流程的有效期，超时后state作废
+const oidcFlowTTL = 10 * time.Minute
+
+var (
+	ErrOIDCInvalidState    = errors.New("invalid or expired state")
+	ErrOIDCNotProvisioned  = errors.New("user is not provisioned")
+	ErrOIDCMissingIDToken  = errors.New("token response has no id_token")
+	ErrOIDCNonceMismatch   = errors.New("id token nonce mismatch")
+	ErrOIDCMissingIdentity = errors.New("id token has no subject")
+)
+
+// OIDCIdentity 从ID Token中解析出的身份信息
+type OIDCIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Roles    []string // 角色声明中的原始值
+}
+
+// oidcFlow 一次进行中的登录流程，签名后保存在浏览器cookie中，服务端不保存状态
+type oidcFlow struct {
+	State       string `json:"s"`
+	Nonce       string `json:"n"`
+	Verifier    string `json:"v"` // PKCE code_verifier
+	RedirectURL string `json:"r"`
+	ExpiresAt   int64  `json:"e"`
+}
+
+// OIDCService OpenID Connect 单点登录服务
+// 使用授权码模式 + PKCE；提供方元数据在首次使用时通过 discovery 获取，获取失败会在下次登录时重试
+type OIDCService struct {
+	cfg *config.Config
+	key []byte // 登录流程cookie的签名密钥，每次启动随机生成
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// OIDCSvc 未配置 OIDC_ISSUER 时为nil
+var OIDCSvc *OIDCService
+
+// InitOIDCService 初始化单点登录服务
+func InitOIDCService(cfg *config.Config) {
+	if cfg.OIDCIssuer == "" {
+		return
+	}
+	svc, err := newOIDCService(cfg)
+	if err != nil {
+		log.Fatal("初始化单点登录失败:", err)
+	}
+	OIDCSvc = svc
+	log.Printf("已启用单点登录: %s (%s)", cfg.OIDCProviderName, cfg.OIDCIssuer)
+}
+
+func newOIDCService(cfg *config.Config) (*OIDCService, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate flow key: %v", err)
+	}
+	return &OIDCService{cfg: cfg, key: key}, nil
+}
+
+// Enabled 是否启用了单点登录
+func (s *OIDCService) Enabled() bool {
+	return s != nil
+}
+
+// Name 登录按钮上显示的名称
+func (s *OIDCService) Name() string {
+	return s.cfg.OIDCProviderName
+}
+
+// discover 获取（并缓存）提供方元数据
+func (s *OIDCService) discover(ctx context.Context) (*oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.provider != nil {
+		return s.provider, nil
+	}
+	provider, err := oidc.NewProvider(ctx, s.cfg.OIDCIssuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %v", err)
+	}
+	s.provider = provider
+	return provider, nil
+}
+
+// oauthConfig 构造OAuth2客户端配置
+func (s *OIDCService) oauthConfig(provider *oidc.Provider, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     s.cfg.OIDCClientID,
+		ClientSecret: s.cfg.OIDCClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       s.cfg.OIDCScopes,
+	}
+}
+
+// AuthURL 开始一次登录流程，返回跳转地址和需要保存到cookie中的流程数据
+// redirectURL 为空时使用配置的 OIDC_REDIRECT_URL
+func (s *OIDCService) AuthURL(ctx context.Context, redirectURL string) (string, string, error) {
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if s.cfg.OIDCRedirectURL != "" {
+		redirectURL = s.cfg.OIDCRedirectURL
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	flow := oidcFlow{
+		State:       state,
+		Nonce:       nonce,
+		Verifier:    oauth2.GenerateVerifier(),
+		RedirectURL: redirectURL,
+		ExpiresAt:   time.Now().Add(oidcFlowTTL).Unix(),
+	}
+	cookie, err := s.encodeFlow(flow)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL := s.oauthConfig(provider, redirectURL).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(flow.Verifier),
+	)
+	return authURL, cookie, nil
+}
+
+// Exchange 用授权码换取令牌，并校验ID Token的签名、签发方、受众、有效期和nonce
+// cookie 为 AuthURL 返回的流程数据，state 必须与其一致
+func (s *OIDCService) Exchange(ctx context.Context, cookie, state, code string) (*OIDCIdentity, error) {
+	flow, ok := s.decodeFlow(cookie)
+	if !ok || state == "" || !hmac.Equal([]byte(flow.State), []byte(state)) || time.Now().Unix() > flow.ExpiresAt {
+		return nil, ErrOIDCInvalidState
+	}
+
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.oauthConfig(provider, flow.RedirectURL).Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %v", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrOIDCMissingIDToken
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.cfg.OIDCClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id token: %v", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, ErrOIDCNonceMismatch
+	}
+	if idToken.Subject == "" {
+		return nil, ErrOIDCMissingIdentity
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse claims: %v", err)
+	}
+
+	identity := &OIDCIdentity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		Roles:   claimStrings(claims[s.cfg.OIDCRoleClaim]),
+	}
+	identity.Username, _ = claims[s.cfg.OIDCUsernameClaim].(string)
+	identity.Email, _ = claims["email"].(string)
+	return identity, nil
+}
+
+// encodeFlow 把登录流程编码为 base64url(JSON).签名
+func (s *OIDCService) encodeFlow(flow oidcFlow) (string, error) {
+	data, err := json.Marshal(flow)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode flow: %v", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + s.signFlow(payload), nil
+}
+
+// decodeFlow 校验签名并解析登录流程
+func (s *OIDCService) decodeFlow(cookie string) (oidcFlow, bool) {
+	var flow oidcFlow
+	payload, signature, ok := strings.Cut(cookie, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signFlow(payload))) {
+		return flow, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(data, &flow) != nil {
+		return flow, false
+	}
+	return flow, true
+}
+
+func (s *OIDCService) signFlow(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// mapRole 按映射表把角色声明转换为本地角色，匹配多个时取权限最高的
+func (s *OIDCService) mapRole(values []string) string {
+	rank := map[string]int{models.RoleViewer: 1, models.RoleUploader: 2, models.RoleAdmin: 3}
+	role := ""
+	for _, value := range values {
+		if mapped, ok := s.cfg.OIDCRoleMapping[value]; ok && rank[mapped] > rank[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// ResolveUser 根据身份找到本地用户，首次登录时按配置自动创建
+// 已存在的用户在角色声明命中映射时同步角色；不会与同名的本地账号关联
+func (s *OIDCService) ResolveUser(identity *OIDCIdentity) (*models.User, error) {
+	db := database.GetDB().DB
+	mapped := s.mapRole(identity.Roles)
+
+	var user models.User
+	err := db.Where("oidc_issuer = ? AND oidc_subject = ?", identity.Issuer, identity.Subject).First(&user).Error
+	if err == nil {
+		if mapped != "" && mapped != user.Role && !s.isLastActiveAdmin(&user) {
+			if err := db.Model(&user).Update("role", mapped).Error; err != nil {
+				return nil, fmt.Errorf("failed to sync role: %v", err)
+			}
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find user: %v", err)
+	}
+
+	if !s.cfg.OIDCAutoProvision {
+		return nil, ErrOIDCNotProvisioned
+	}
+	role := mapped
+	if role == "" {
+		role = s.cfg.OIDCDefaultRole
+	}
+	if !models.IsValidRole(role) {
+		// 默认角色设为 none 等无效值时，只允许命中映射的用户登录
+		return nil, ErrOIDCNotProvisioned
+	}
+
+	username, err := s.availableUsername(identity)
+	if err != nil {
+		return nil, err
+	}
+	user = models.User{
+		Username:    username,
+		Role:        role,
+		OidcIssuer:  identity.Issuer,
+		OidcSubject: identity.Subject,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %v", err)
+	}
+	log.Printf("单点登录自动创建用户: %s (角色: %s)", user.Username, user.Role)
+	return &user, nil
+}
+
+// isLastActiveAdmin 同步角色时不能降级最后一个管理员
+func (s *OIDCService) isLastActiveAdmin(user *models.User) bool {
+	if user.Role != models.RoleAdmin {
+		return false
+	}
+	var count int64
+	database.GetDB().DB.Model(&models.User{}).
+		Where("role = ? AND disabled = ? AND id <> ?", models.RoleAdmin, false, user.Id).
+		Count(&count)
+	return count == 0
+}
+
+var usernameCleaner = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// availableUsername 生成不与现有用户冲突的用户名
+func (s *OIDCService) availableUsername(identity *OIDCIdentity) (string, error) {
+	base := identity.Username
+	if base == "" && identity.Email != "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = usernameCleaner.ReplaceAllString(base, "")
+	if len(base) > 16 {
+		base = base[:16]
+	}
+	if len(base) < 3 {
+		base = "sso_" + base
+	}
+
+	db := database.GetDB().DB
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s_%d", base, i)
+		}
+		var count int64
+		if err := db.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", fmt.Errorf("failed to check username: %v", err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find available username for %s", base)
+}
+
+// claimStrings 把字符串或字符串数组形式的声明统一为切片
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(strings.ReplaceAll(v, ",", " "))
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// randomHex 生成指定字节数的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"oneimg/backend/config"
+)
+
+// mockOIDCProvider 本地模拟的身份提供方，实现 discovery、JWKS 和令牌接口
+type mockOIDCProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+// mockAuthorization 授权接口收到的参数
+type mockAuthorization struct {
+	nonce     string
+	challenge string
+	subject   string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDCProvider{t: t, key: key, codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize 模拟用户在身份提供方完成登录，返回授权码
+func (m *mockOIDCProvider) authorize(authURL, subject string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		m.t.Fatalf("auth url has no PKCE challenge: %s", authURL)
+	}
+	if query.Get("nonce") == "" {
+		m.t.Fatalf("auth url has no nonce: %s", authURL)
+	}
+
+	code := "code-" + subject
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{
+		nonce:     query.Get("nonce"),
+		challenge: query.Get("code_challenge"),
+		subject:   subject,
+	}
+	m.mu.Unlock()
+	return code
+}
+
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	m.mu.Lock()
+	auth, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	now := time.Now()
+	idToken := m.sign(map[string]any{
+		"iss":                m.server.URL,
+		"sub":                auth.subject,
+		"aud":                "client",
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Minute).Unix(),
+		"nonce":              auth.nonce,
+		"preferred_username": "alice",
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+// sign 生成RS256签名的JWT
+func (m *mockOIDCProvider) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "test"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestOIDCService(t *testing.T, issuer string) *OIDCService {
+	svc, err := newOIDCService(&config.Config{
+		OIDCIssuer:        issuer,
+		OIDCClientID:      "client",
+		OIDCClientSecret:  "secret",
+		OIDCScopes:        []string{"openid", "profile"},
+		OIDCUsernameClaim: "preferred_username",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func stateOf(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("state")
+}
+
+func TestOIDCExchange(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	svc := newTestOIDCService(t, provider.server.URL)
+	ctx := context.Background()
+
+	authURL, flow, err := svc.AuthURL(ctx, "http://app.test/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := provider.authorize(authURL, "user-1")
+
+	identity, err := svc.Exchange(ctx, flow, stateOf(t, authURL), code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "user-1" || identity.Username != "alice" || identity.Issuer != provider.server.URL {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+}
+
+func TestOIDCExchangeRejectsState(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	svc := newTestOIDCService(t, provider.server.URL)
+	ctx := context.Background()
+
+	authURL, flow, err := svc.AuthURL(ctx, "http://app.test/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := stateOf(t, authURL)
+	payload, signature, _ := strings.Cut(flow, ".")
+
+	cases := map[string]struct{ flow, state string }{
+		"wrong state":      {flow, "other"},
+		"empty state":      {flow, ""},
+		"missing cookie":   {"", state},
+		"forged signature": {payload + ".AAAA", state},
+		"tampered payload": {base64.RawURLEncoding.EncodeToString([]byte(`{"s":"`+state+`"}`)) + "." + signature, state},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			code := provider.authorize(authURL, "user-1")
+			_, err := svc.Exchange(ctx, tc.flow, tc.state, code)
+			if !errors.Is(err, ErrOIDCInvalidState) {
+				t.Fatalf("expected ErrOIDCInvalidState, got %v", err)
+			}
+		})
+	}
+
+	// 其他实例签发的流程数据同样无效
+	other := newTestOIDCService(t, provider.server.URL)
+	if _, err := other.Exchange(ctx, flow, state, provider.authorize(authURL, "user-1")); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("expected ErrOIDCInvalidState, got %v", err)
+	}
+}
+
+func TestOIDCExchangeRejectsNonceMismatch(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	svc := newTestOIDCService(t, provider.server.URL)
+	ctx := context.Background()
+
+	authURL, flow, err := svc.AuthURL(ctx, "http://app.test/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := provider.authorize(authURL, "user-1")
+	provider.mu.Lock()
+	auth := provider.codes[code]
+	auth.nonce = "replayed"
+	provider.codes[code] = auth
+	provider.mu.Unlock()
+
+	if _, err := svc.Exchange(ctx, flow, stateOf(t, authURL), code); !errors.Is(err, ErrOIDCNonceMismatch) {
+		t.Fatalf("expected ErrOIDCNonceMismatch, got %v", err)
+	}
+}
+
+func TestOIDCExchangeRequiresPKCEVerifier(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	svc := newTestOIDCService(t, provider.server.URL)
+	ctx := context.Background()
+
+	// 授权码属于另一次登录流程，当前流程的verifier无法通过提供方的PKCE校验
+	authURL, flow, err := svc.AuthURL(ctx, "http://app.test/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherURL, _, err := svc.AuthURL(ctx, "http://app.test/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := provider.authorize(otherURL, "user-2")
+
+	if _, err := svc.Exchange(ctx, flow, stateOf(t, authURL), code); err == nil {
+		t.Fatal("expected exchange to fail with a mismatched code verifier")
+	}
+}
+
+func TestOIDCExchangeRejectsExpiredFlow(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	svc := newTestOIDCService(t, provider.server.URL)
+	ctx := context.Background()
+
+	authURL, flow, err := svc.AuthURL(ctx, "http://app.test/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, ok := svc.decodeFlow(flow)
+	if !ok {
+		t.Fatal("failed to decode flow")
+	}
+	decoded.ExpiresAt = time.Now().Add(-time.Second).Unix()
+	expired, err := svc.encodeFlow(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := provider.authorize(authURL, "user-1")
+	if _, err := svc.Exchange(ctx, expired, stateOf(t, authURL), code); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("expected ErrOIDCInvalidState, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"oneimg/backend/config"
+)
+
+// PasswordPolicy 密码强度策略，适用于注册、创建用户和修改密码
+type PasswordPolicy struct {
+	minLength     int
+	requireUpper  bool
+	requireLower  bool
+	requireDigit  bool
+	requireSymbol bool
+}
+
+var PasswordSvc *PasswordPolicy
+
+// InitPasswordPolicy 初始化密码策略
+func InitPasswordPolicy(cfg *config.Config) {
+	PasswordSvc = &PasswordPolicy{
+		minLength:     cfg.PasswordMinLength,
+		requireUpper:  cfg.PasswordRequireUpper,
+		requireLower:  cfg.PasswordRequireLower,
+		requireDigit:  cfg.PasswordRequireDigit,
+		requireSymbol: cfg.PasswordRequireSymbol,
+	}
+}
+
+// Validate 检查密码是否符合策略，返回的错误信息可直接展示给用户
+func (p *PasswordPolicy) Validate(password, username string) error {
+	if len([]rune(password)) < p.minLength {
+		return fmt.Errorf("密码长度不能少于%d位", p.minLength)
+	}
+	if len(password) > 72 {
+		// bcrypt只使用前72字节
+		return errors.New("密码长度不能超过72字节")
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return errors.New("密码不能与用户名相同")
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var missing []string
+	if p.requireUpper && !hasUpper {
+		missing = append(missing, "大写字母")
+	}
+	if p.requireLower && !hasLower {
+		missing = append(missing, "小写字母")
+	}
+	if p.requireDigit && !hasDigit {
+		missing = append(missing, "数字")
+	}
+	if p.requireSymbol && !hasSymbol {
+		missing = append(missing, "特殊符号")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("密码必须包含%s", strings.Join(missing, "、"))
+	}
+	return nil
+}
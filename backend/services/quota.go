@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+)
+
+const (
+	QuotaBytesExceeded  = "quota_bytes_exceeded"
+	QuotaImagesExceeded = "quota_images_exceeded"
+	QuotaDailyExceeded  = "quota_daily_exceeded"
+)
+
+// QuotaLimits 用户生效的配额，0表示不限制
+type QuotaLimits struct {
+	Bytes  int64 `json:"bytes"`
+	Images int64 `json:"images"`
+	Daily  int64 `json:"daily"`
+}
+
+// QuotaUsage 用户当前用量
+type QuotaUsage struct {
+	Bytes  int64 `json:"bytes"`
+	Images int64 `json:"images"`
+	Daily  int64 `json:"daily"`
+}
+
+// QuotaError 超出配额的错误，Code 用于前端区分具体原因
+type QuotaError struct {
+	Code    string
+	Message string
+}
+
+func (e *QuotaError) Error() string {
+	return e.Message
+}
+
+// QuotaService 用户上传配额服务
+// 个人配额优先于角色默认配额；同一用户的检查和入库串行执行，避免并发上传绕过限制
+type QuotaService struct {
+	roleQuotas map[string]config.QuotaLimit
+	locks      [quotaLockStripes]sync.Mutex
+}
+
+// quotaLockStripes 配额锁的分段数，按用户ID取模选择，内存占用固定
+const quotaLockStripes = 64
+
+var QuotaSvc *QuotaService
+
+// InitQuotaService 初始化配额服务
+func InitQuotaService(cfg *config.Config) {
+	QuotaSvc = &QuotaService{roleQuotas: cfg.RoleQuotas}
+}
+
+// Limits 计算用户生效的配额
+func (s *QuotaService) Limits(user *models.User) QuotaLimits {
+	defaults := s.roleQuotas[user.Role]
+	limits := QuotaLimits{
+		Bytes:  defaults.Bytes,
+		Images: defaults.Images,
+		Daily:  defaults.Daily,
+	}
+	if user.QuotaBytes != nil {
+		limits.Bytes = *user.QuotaBytes
+	}
+	if user.QuotaImages != nil {
+		limits.Images = *user.QuotaImages
+	}
+	if user.QuotaDaily != nil {
+		limits.Daily = *user.QuotaDaily
+	}
+	return limits
+}
+
+// Usage 统计用户已用空间、图片数和今日上传数
+// 回收站中的图片文件仍在磁盘上，同样计入配额
+func (s *QuotaService) Usage(userID int) (QuotaUsage, error) {
+	db := database.GetDB().DB.Unscoped()
+
+	var usage QuotaUsage
+	err := db.Model(&models.Image{}).
+		Select("COUNT(*) as images, COALESCE(SUM(file_size), 0) as bytes").
+		Where("user_id = ?", userID).
+		Scan(&usage).Error
+	if err != nil {
+		return usage, fmt.Errorf("failed to count usage: %v", err)
+	}
+
+	// 与图片列表的日期筛选使用相同的时区划分日期
+	midnight := database.DayStart(time.Now())
+	if err := db.Model(&models.Image{}).
+		Where("user_id = ? AND created_at >= ?", userID, midnight).
+		Count(&usage.Daily).Error; err != nil {
+		return usage, fmt.Errorf("failed to count daily uploads: %v", err)
+	}
+	return usage, nil
+}
+
+// Check 检查用户再上传 size 字节的图片是否会超出配额
+func (s *QuotaService) Check(userID int, size int64) error {
+	var user models.User
+	if err := database.GetDB().DB.First(&user, userID).Error; err != nil {
+		return fmt.Errorf("failed to load user: %v", err)
+	}
+
+	limits := s.Limits(&user)
+	if limits.Bytes == 0 && limits.Images == 0 && limits.Daily == 0 {
+		return nil
+	}
+
+	usage, err := s.Usage(userID)
+	if err != nil {
+		return err
+	}
+
+	if limits.Images > 0 && usage.Images+1 > limits.Images {
+		return &QuotaError{
+			Code:    QuotaImagesExceeded,
+			Message: fmt.Sprintf("图片数量已达上限（%d张）", limits.Images),
+		}
+	}
+	if limits.Daily > 0 && usage.Daily+1 > limits.Daily {
+		return &QuotaError{
+			Code:    QuotaDailyExceeded,
+			Message: fmt.Sprintf("今日上传数量已达上限（%d张）", limits.Daily),
+		}
+	}
+	if limits.Bytes > 0 && usage.Bytes+size > limits.Bytes {
+		return &QuotaError{
+			Code: QuotaBytesExceeded,
+			Message: fmt.Sprintf("存储空间不足（已用 %s / 共 %s，本次需要 %s）",
+				formatBytes(usage.Bytes), formatBytes(limits.Bytes), formatBytes(size)),
+		}
+	}
+	return nil
+}
+
+// Lock 锁定用户的配额检查，返回解锁函数
+func (s *QuotaService) Lock(userID int) func() {
+	mu := &s.locks[uint(userID)%quotaLockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// formatBytes 以易读的单位显示字节数
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
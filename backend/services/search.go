@@ -0,0 +1,335 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"unicode"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxSearchTerms   = 10  // 搜索词数量上限
+	highlightSnippet = 160 // 长文本高亮片段的长度（字符）
+	searchIndexBatch = 500 // 每批更新索引的图片数量，避免过长的 IN 列表
+)
+
+// SQLite bm25 各列权重：name, title, description, alt_text, tags, exif
+const fts5Rank = "bm25(images_fts, 5.0, 10.0, 2.0, 2.0, 5.0, 1.0)"
+
+// MySQL 全文检索列
+const fulltextColumns = "image_search.name, image_search.title, image_search.description, image_search.alt_text, image_search.tags, image_search.exif"
+
+// SearchService 图片全文检索服务
+// 检索方式由 database.InitDB 按数据库类型决定，索引在图片信息或标签变化时同步更新
+type SearchService struct {
+	mode string
+}
+
+var SearchSvc *SearchService
+
+// InitSearchService 初始化检索服务，索引与图片数量不一致时重建索引
+func InitSearchService() {
+	SearchSvc = &SearchService{mode: database.SearchMode()}
+	if err := SearchSvc.rebuildIfStale(); err != nil {
+		log.Printf("重建搜索索引失败: %v", err)
+	}
+}
+
+// searchRow 索引中的一行
+type searchRow struct {
+	ImageId     int
+	Name        string
+	Title       string
+	Description string
+	AltText     string
+	Tags        string
+	Exif        string
+}
+
+// searchFields 图片参与检索的字段，键名与高亮结果一致
+func searchFields(image *models.Image) searchRow {
+	name := image.FileName
+	if image.OriginalName != "" {
+		name = image.OriginalName + " " + image.FileName
+	}
+	return searchRow{
+		ImageId:     image.Id,
+		Name:        name,
+		Title:       image.Title,
+		Description: image.Description,
+		AltText:     image.AltText,
+		Tags:        strings.Join(image.Tags, ", "),
+		Exif:        strings.TrimSpace(strings.Join([]string{image.CameraMake, image.CameraModel, image.LensModel}, " ")),
+	}
+}
+
+// Index 更新图片的检索索引（上传、修改信息、标签变化后调用）
+// 需要与修改图片或标签的操作在同一事务中调用，索引写入失败时整个修改回滚，避免索引内容过期
+func (s *SearchService) Index(tx *gorm.DB, imageIDs []int) error {
+	if s.mode == database.SearchLike {
+		return nil
+	}
+	for start := 0; start < len(imageIDs); start += searchIndexBatch {
+		end := min(start+searchIndexBatch, len(imageIDs))
+		if err := s.index(tx, imageIDs[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SearchService) index(tx *gorm.DB, imageIDs []int) error {
+	var images []models.Image
+	if err := tx.Unscoped().Where("id IN ?", imageIDs).Find(&images).Error; err != nil {
+		return fmt.Errorf("failed to load images: %v", err)
+	}
+	TagSvc.attach(tx, images)
+
+	if err := s.Remove(tx, imageIDs); err != nil {
+		return err
+	}
+	for i := range images {
+		row := searchFields(&images[i])
+		var err error
+		if s.mode == database.SearchFTS5 {
+			err = tx.Exec("INSERT INTO images_fts (rowid, name, title, description, alt_text, tags, exif) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				row.ImageId, row.Name, row.Title, row.Description, row.AltText, row.Tags, row.Exif).Error
+		} else {
+			err = tx.Exec("INSERT INTO image_search (image_id, name, title, description, alt_text, tags, exif) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				row.ImageId, row.Name, row.Title, row.Description, row.AltText, row.Tags, row.Exif).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to index image %d: %v", row.ImageId, err)
+		}
+	}
+	return nil
+}
+
+// Remove 从索引中删除图片（图片被永久删除时在同一事务中调用）
+func (s *SearchService) Remove(tx *gorm.DB, imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	switch s.mode {
+	case database.SearchFTS5:
+		return tx.Exec("DELETE FROM images_fts WHERE rowid IN ?", imageIDs).Error
+	case database.SearchFulltext:
+		return tx.Exec("DELETE FROM image_search WHERE image_id IN ?", imageIDs).Error
+	}
+	return nil
+}
+
+// rebuildIfStale 索引条数与图片数量不一致时（首次启用或升级后）重建索引
+// 回收站中的图片也保留在索引中，查询时由图片表的软删除条件排除
+func (s *SearchService) rebuildIfStale() error {
+	if s.mode == database.SearchLike {
+		return nil
+	}
+	db := database.GetDB().DB
+
+	table := "image_search"
+	if s.mode == database.SearchFTS5 {
+		table = "images_fts"
+	}
+	var indexed, total int64
+	if err := db.Raw("SELECT COUNT(*) FROM " + table).Scan(&indexed).Error; err != nil {
+		return err
+	}
+	if err := db.Unscoped().Model(&models.Image{}).Count(&total).Error; err != nil {
+		return err
+	}
+	if indexed == total {
+		return nil
+	}
+
+	var ids []int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Image{}).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		return s.Index(tx, ids)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("已重建搜索索引，共%d张图片", len(ids))
+	return nil
+}
+
+// searchTerms 把搜索内容拆分为检索词（只保留字母和数字）
+func searchTerms(search string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// Apply 添加搜索条件，返回按相关度排序的表达式（LIKE 匹配时为nil）
+// 每个检索词都按前缀匹配，多个检索词需同时命中
+// 排序表达式不能再与其他 Order 合并，否则会被 GORM 丢弃
+func (s *SearchService) Apply(query *gorm.DB, search string) (*gorm.DB, any) {
+	terms := searchTerms(search)
+	if s.mode == database.SearchLike || len(terms) == 0 {
+		return s.applyLike(query, search), nil
+	}
+
+	// FTS5 的 unicode61 分词把连续的中日韩文字当作一个词，只能按前缀命中，
+	// 包含这类文字时逐个检索词按 LIKE 子串匹配（MySQL 使用 ngram 分词，不受影响）
+	if s.mode == database.SearchFTS5 && hasCJK(terms) {
+		for _, term := range terms {
+			query = s.applyLike(query, term)
+		}
+		return query, nil
+	}
+
+	if s.mode == database.SearchFTS5 {
+		parts := make([]string, 0, len(terms))
+		for _, term := range terms {
+			parts = append(parts, `"`+term+`"*`)
+		}
+		query = query.Joins("JOIN images_fts ON images_fts.rowid = images.id").
+			Where("images_fts MATCH ?", strings.Join(parts, " "))
+		return query, clause.OrderBy{Expression: clause.Expr{SQL: fts5Rank + ", images.id DESC"}}
+	}
+
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts = append(parts, "+"+term+"*")
+	}
+	against := strings.Join(parts, " ")
+	query = query.Joins("JOIN image_search ON image_search.image_id = images.id").
+		Where("MATCH("+fulltextColumns+") AGAINST(? IN BOOLEAN MODE)", against)
+	return query, clause.OrderBy{Expression: clause.Expr{
+		SQL:  "MATCH(" + fulltextColumns + ") AGAINST(? IN BOOLEAN MODE) DESC, images.id DESC",
+		Vars: []any{against},
+	}}
+}
+
+// hasCJK 检索词中是否包含中日韩文字
+func hasCJK(terms []string) bool {
+	for _, term := range terms {
+		for _, r := range term {
+			if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyLike 不支持全文检索时的 LIKE 匹配
+func (s *SearchService) applyLike(query *gorm.DB, search string) *gorm.DB {
+	pattern := "%" + EscapeLike(search) + "%"
+	tagged := database.GetDB().DB.Model(&models.ImageTag{}).
+		Select("image_tags.image_id").
+		Joins("JOIN tags ON tags.id = image_tags.tag_id").
+		Where("tags.name LIKE ? ESCAPE '!'", pattern)
+
+	return query.Where("(images.file_name LIKE @p ESCAPE '!' OR images.original_name LIKE @p ESCAPE '!' OR images.title LIKE @p ESCAPE '!'"+
+		" OR images.description LIKE @p ESCAPE '!' OR images.alt_text LIKE @p ESCAPE '!'"+
+		" OR images.camera_make LIKE @p ESCAPE '!' OR images.camera_model LIKE @p ESCAPE '!' OR images.lens_model LIKE @p ESCAPE '!'"+
+		" OR images.id IN (@tagged))",
+		map[string]any{"p": pattern, "tagged": tagged})
+}
+
+// Highlight 为搜索结果填充高亮片段，命中的内容用 <mark> 包裹，其余内容已做HTML转义
+func (s *SearchService) Highlight(images []models.Image, search string) {
+	terms := searchTerms(search)
+	if len(terms) == 0 {
+		if search = strings.TrimSpace(strings.ToLower(search)); search == "" {
+			return
+		}
+		terms = []string{search}
+	}
+
+	for i := range images {
+		row := searchFields(&images[i])
+		fields := map[string]string{
+			"name":        row.Name,
+			"title":       row.Title,
+			"description": row.Description,
+			"alt_text":    row.AltText,
+			"tags":        row.Tags,
+			"exif":        row.Exif,
+		}
+		highlights := map[string]string{}
+		for key, text := range fields {
+			if snippet, ok := highlightText(text, terms); ok {
+				highlights[key] = snippet
+			}
+		}
+		if len(highlights) > 0 {
+			images[i].Highlights = highlights
+		}
+	}
+}
+
+// highlightText 标记文本中所有命中的检索词（不区分大小写），过长的文本截取第一个命中位置附近的片段
+func highlightText(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != term {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if len(runes) > highlightSnippet {
+		start = max(first-highlightSnippet/4, 0)
+		end = min(start+highlightSnippet, len(runes))
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// initTestDB 初始化内存数据库，测试结束后清空图片和标签
+func initTestDB(t *testing.T) *gorm.DB {
+	database.InitDB(&config.Config{SqlitePath: "file:services_test?mode=memory&cache=shared"})
+	SearchSvc = &SearchService{mode: database.SearchLike}
+	TagSvc = &TagService{}
+
+	db := database.GetDB().DB
+	t.Cleanup(func() {
+		db.Where("1 = 1").Delete(&models.ImageTag{})
+		db.Where("1 = 1").Delete(&models.Tag{})
+		db.Unscoped().Where("1 = 1").Delete(&models.Image{})
+	})
+	return db
+}
+
+// newTestSearchDB 初始化内存数据库并写入测试图片
+func newTestSearchDB(t *testing.T) {
+	db := initTestDB(t)
+	images := []models.Image{
+		{Url: "/uploads/a.webp", FileName: "a.webp", OriginalName: "发票扫描.jpg", CreatedAt: time.Now()},
+		{Url: "/uploads/b.webp", FileName: "b.webp", OriginalName: "合同扫描件 2024.png", CreatedAt: time.Now()},
+		{Url: "/uploads/c.webp", FileName: "c.webp", OriginalName: "holiday.jpg", Title: "海边日落", CreatedAt: time.Now()},
+	}
+	if err := db.Create(&images).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func searchNames(t *testing.T, svc *SearchService, search string) []string {
+	query, _ := svc.Apply(database.GetDB().DB.Model(&models.Image{}), search)
+	var names []string
+	if err := query.Pluck("images.original_name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSearchMatchesCJKSubstrings(t *testing.T) {
+	newTestSearchDB(t)
+
+	// FTS5 模式下中日韩检索词同样按子串匹配，不依赖 images_fts 的分词结果
+	for _, mode := range []string{database.SearchLike, database.SearchFTS5} {
+		svc := &SearchService{mode: mode}
+		cases := map[string][]string{
+			"扫描": {"发票扫描.jpg", "合同扫描件 2024.png"},
+			"发票": {"发票扫描.jpg"},
+			"描件": {"合同扫描件 2024.png"},
+			"日落": {"holiday.jpg"},
+			"收据": nil,
+		}
+		for search, want := range cases {
+			got := searchNames(t, svc, search)
+			if len(got) != len(want) {
+				t.Fatalf("mode %s, search %q: expected %v, got %v", mode, search, want, got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("mode %s, search %q: expected %v, got %v", mode, search, want, got)
+				}
+			}
+		}
+	}
+
+	// 多个检索词需同时命中
+	svc := &SearchService{mode: database.SearchFTS5}
+	if got := searchNames(t, svc, "扫描 2024"); len(got) != 1 || got[0] != "合同扫描件 2024.png" {
+		t.Fatalf("expected only the contract scan, got %v", got)
+	}
+}
+
+func TestHasCJK(t *testing.T) {
+	cases := map[string]bool{
+		"扫描":        true,
+		"スキャン":      true,
+		"스캔":        true,
+		"scan 2024": false,
+		"café":      false,
+	}
+	for search, want := range cases {
+		if got := hasCJK(searchTerms(search)); got != want {
+			t.Errorf("hasCJK(%q) = %v, want %v", search, got, want)
+		}
+	}
+}
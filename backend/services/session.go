@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+)
+
+// 最近活跃时间的更新间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
+// SessionService 服务端登录会话管理
+type SessionService struct {
+	maxAge time.Duration
+}
+
+var SessionSvc *SessionService
+
+// InitSessionService 初始化会话管理服务，并定期清理过期会话记录
+func InitSessionService(cfg *config.Config) {
+	SessionSvc = &SessionService{maxAge: cfg.SessionMaxAge}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			SessionSvc.CleanupExpired()
+		}
+	}()
+}
+
+// Create 为用户创建新的登录会话
+func (s *SessionService) Create(userID int, ip, userAgent string) (*models.UserSession, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %v", err)
+	}
+
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	now := time.Now()
+	record := &models.UserSession{
+		UserId:     userID,
+		Token:      hex.EncodeToString(buf),
+		Device:     DescribeDevice(userAgent),
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.maxAge),
+	}
+	if err := database.GetDB().DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Validate 校验会话token是否仍然有效，并按需刷新最近活跃时间和IP
+func (s *SessionService) Validate(userID int, token, ip string) (*models.UserSession, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	db := database.GetDB().DB
+	var record models.UserSession
+	if err := db.Where("token = ? AND user_id = ?", token, userID).First(&record).Error; err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	if now.After(record.ExpiresAt) {
+		db.Delete(&record)
+		return nil, false
+	}
+
+	if now.Sub(record.LastSeenAt) > sessionTouchInterval || record.IP != ip {
+		record.LastSeenAt = now
+		record.IP = ip
+		db.Model(&record).Updates(map[string]any{
+			"last_seen_at": now,
+			"ip":           ip,
+		})
+	}
+	return &record, true
+}
+
+// List 列出用户所有有效会话，最近活跃的在前
+func (s *SessionService) List(userID int) ([]models.UserSession, error) {
+	var records []models.UserSession
+	err := database.GetDB().DB.
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&records).Error
+	return records, err
+}
+
+// Revoke 吊销用户的某个会话
+func (s *SessionService) Revoke(userID, sessionID int) (bool, error) {
+	result := database.GetDB().DB.
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Delete(&models.UserSession{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeByToken 按token吊销会话（用于退出登录）
+func (s *SessionService) RevokeByToken(token string) error {
+	if token == "" {
+		return nil
+	}
+	return database.GetDB().DB.Where("token = ?", token).Delete(&models.UserSession{}).Error
+}
+
+// RevokeAll 吊销用户的全部会话，exceptID 大于0时保留该会话
+func (s *SessionService) RevokeAll(userID, exceptID int) (int64, error) {
+	query := database.GetDB().DB.Where("user_id = ?", userID)
+	if exceptID > 0 {
+		query = query.Where("id <> ?", exceptID)
+	}
+	result := query.Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
+
+// CleanupExpired 删除已过期的会话记录
+func (s *SessionService) CleanupExpired() {
+	result := database.GetDB().DB.Where("expires_at <= ?", time.Now()).Delete(&models.UserSession{})
+	if result.Error != nil {
+		log.Printf("清理过期会话失败: %v", result.Error)
+	}
+}
+
+// DescribeDevice 根据User-Agent粗略识别浏览器和操作系统
+func DescribeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "未知设备"
+	}
+
+	var browser string
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	default:
+		browser = "其他客户端"
+	}
+
+	var os string
+	switch {
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " / " + os
+}
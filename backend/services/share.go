@@ -0,0 +1,127 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShareNotFound = errors.New("share link not found")
+	ErrShareExpired  = errors.New("share link expired or used up")
+)
+
+// ShareService 图片分享链接服务
+// 分享链接可以访问私有图片，回收站中的图片不可访问
+type ShareService struct {
+	secret []byte // 签发密码验证凭据
+}
+
+var ShareSvc *ShareService
+
+// InitShareService 初始化分享链接服务
+func InitShareService(cfg *config.Config) {
+	ShareSvc = &ShareService{secret: []byte(cfg.SessionSecret)}
+}
+
+// Create 为图片生成分享链接，password 为空表示无需密码
+func (s *ShareService) Create(imageID, createdBy int, expiresAt *time.Time, maxViews int, password string) (*models.ShareLink, error) {
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.ShareLink{
+		ImageId:   imageID,
+		Token:     token,
+		MaxViews:  maxViews,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %v", err)
+		}
+		link.PasswordHash = string(hash)
+	}
+	if err := database.GetDB().DB.Create(link).Error; err != nil {
+		return nil, fmt.Errorf("failed to create share link: %v", err)
+	}
+	return link, nil
+}
+
+// Find 查找分享链接及其图片，链接已过期、次数用完或图片已删除时返回错误
+func (s *ShareService) Find(token string) (*models.ShareLink, *models.Image, error) {
+	if token == "" {
+		return nil, nil, ErrShareNotFound
+	}
+
+	db := database.GetDB().DB
+	var link models.ShareLink
+	if err := db.Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, nil, ErrShareNotFound
+	}
+	var image models.Image
+	if err := db.First(&image, link.ImageId).Error; err != nil {
+		return nil, nil, ErrShareNotFound
+	}
+	if !link.IsUsable(time.Now()) {
+		return &link, &image, ErrShareExpired
+	}
+	return &link, &image, nil
+}
+
+// CheckPassword 校验分享密码
+func (s *ShareService) CheckPassword(link *models.ShareLink, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) == nil
+}
+
+// Proof 密码验证通过后保存在cookie中的凭据，修改密码后旧凭据失效
+func (s *ShareService) Proof(link *models.ShareLink) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(link.Token + ":" + link.PasswordHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyProof 校验cookie中的密码验证凭据
+func (s *ShareService) VerifyProof(link *models.ShareLink, proof string) bool {
+	return hmac.Equal([]byte(proof), []byte(s.Proof(link)))
+}
+
+// RecordView 记录一次访问，条件更新保证并发访问时不会超出次数限制
+func (s *ShareService) RecordView(link *models.ShareLink) error {
+	result := database.GetDB().DB.Model(&models.ShareLink{}).
+		Where("id = ? AND (max_views = 0 OR views < max_views) AND (expires_at IS NULL OR expires_at > ?)", link.Id, time.Now()).
+		Update("views", gorm.Expr("views + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to record view: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrShareExpired
+	}
+	link.Views++
+	return nil
+}
+
+// DetachImages 删除图片的所有分享链接（图片被永久删除时在同一事务中调用）
+func (s *ShareService) DetachImages(tx *gorm.DB, imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("image_id IN ?", imageIDs).Delete(&models.ShareLink{}).Error; err != nil {
+		return fmt.Errorf("failed to delete share links: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestSignedURLService() *SignedURLService {
+	return newSignedURLService("test-signing-secret-0123456789abcdef", time.Hour, 24*time.Hour)
+}
+
+func TestSignedURLVerify(t *testing.T) {
+	svc := newTestSignedURLService()
+	query, expiresAt, err := svc.Sign("/uploads/2025/09/a.webp", url.Values{"w": {"200"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Fatalf("unexpected default expiry: %v", until)
+	}
+	if err := svc.Verify("/uploads/2025/09/a.webp", query); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+}
+
+func TestSignedURLRejectsTampering(t *testing.T) {
+	svc := newTestSignedURLService()
+	path := "/uploads/2025/09/a.webp"
+	query, _, err := svc.Sign(path, url.Values{"w": {"200"}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := func(change func(url.Values)) url.Values {
+		q := url.Values{}
+		for key, values := range query {
+			q[key] = append([]string(nil), values...)
+		}
+		change(q)
+		return q
+	}
+
+	cases := map[string]struct {
+		path  string
+		query url.Values
+	}{
+		"other path":    {"/uploads/2025/09/b.webp", query},
+		"changed param": {path, tamper(func(q url.Values) { q.Set("w", "2000") })},
+		"added param":   {path, tamper(func(q url.Values) { q.Set("h", "100") })},
+		"removed param": {path, tamper(func(q url.Values) { q.Del("w") })},
+		"extended expiry": {path, tamper(func(q url.Values) {
+			q.Set(SignedURLExpires, strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10))
+		})},
+		"missing expiry":    {path, tamper(func(q url.Values) { q.Del(SignedURLExpires) })},
+		"forged signature":  {path, tamper(func(q url.Values) { q.Set(SignedURLSignature, "AAAA") })},
+		"missing signature": {path, tamper(func(q url.Values) { q.Del(SignedURLSignature) })},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := svc.Verify(tc.path, tc.query); !errors.Is(err, ErrSignatureInvalid) {
+				t.Fatalf("expected ErrSignatureInvalid, got %v", err)
+			}
+		})
+	}
+
+	other := newSignedURLService("another-signing-secret-0123456789", time.Hour, 24*time.Hour)
+	if err := other.Verify(path, query); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected ErrSignatureInvalid for a different secret, got %v", err)
+	}
+}
+
+func TestSignedURLExpiry(t *testing.T) {
+	svc := newTestSignedURLService()
+	path := "/uploads/2025/09/a.webp"
+
+	// 手动构造已过期但签名正确的参数
+	query := url.Values{}
+	query.Set(SignedURLExpires, strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))
+	query.Set(SignedURLSignature, svc.signature(path, query))
+	if err := svc.Verify(path, query); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expected ErrSignatureExpired, got %v", err)
+	}
+
+	if _, _, err := svc.Sign(path, nil, 25*time.Hour); !errors.Is(err, ErrSignedURLTTL) {
+		t.Fatalf("expected ErrSignedURLTTL, got %v", err)
+	}
+	if _, _, err := svc.Sign(path, nil, -time.Minute); !errors.Is(err, ErrSignedURLTTL) {
+		t.Fatalf("expected ErrSignedURLTTL, got %v", err)
+	}
+}
+
+func TestSignedURLRequiresSecret(t *testing.T) {
+	for _, secret := range []string{"", "  ", "your-session-secret-key-change-this-in-production"} {
+		svc := newSignedURLService(secret, time.Hour, 24*time.Hour)
+		if svc.Enabled() {
+			t.Fatalf("secret %q should not enable signed urls", secret)
+		}
+		if _, _, err := svc.Sign("/uploads/a.webp", nil, 0); !errors.Is(err, ErrSignedURLOff) {
+			t.Fatalf("expected ErrSignedURLOff, got %v", err)
+		}
+
+		// 用示例密钥伪造的签名同样不被接受
+		forger := &SignedURLService{secret: []byte(secret), defaultTTL: time.Hour, maxTTL: 24 * time.Hour}
+		query := url.Values{}
+		query.Set(SignedURLExpires, strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		query.Set(SignedURLSignature, forger.signature("/uploads/a.webp", query))
+		if err := svc.Verify("/uploads/a.webp", query); !errors.Is(err, ErrSignedURLOff) {
+			t.Fatalf("expected ErrSignedURLOff, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 单张图片最多的标签数
+const MaxTagsPerImage = 50
+
+var ErrTagExists = errors.New("tag already exists")
+
+// TagLimitError 添加标签后超出单张图片的标签上限，ImageIDs 为超出上限的图片
+type TagLimitError struct {
+	ImageIDs []int
+}
+
+func (e *TagLimitError) Error() string {
+	return fmt.Sprintf("每张图片最多%d个标签", MaxTagsPerImage)
+}
+
+// TagSuggestion 标签联想结果
+type TagSuggestion struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// TagService 标签服务，权限检查由调用方完成
+type TagService struct{}
+
+var TagSvc *TagService
+
+// InitTagService 初始化标签服务
+func InitTagService() {
+	TagSvc = &TagService{}
+}
+
+// NormalizeTag 统一标签格式：去掉首尾空白、合并连续空白、转为小写，过长或为空时返回空字符串
+func NormalizeTag(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" || utf8.RuneCountInString(name) > 64 {
+		return ""
+	}
+	return name
+}
+
+// ParseTags 解析逗号分隔的标签列表，去重并忽略无效标签
+func ParseTags(value string) []string {
+	return NormalizeTags(strings.Split(value, ","))
+}
+
+// NormalizeTags 规范化标签列表并去重
+func NormalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		if tag := NormalizeTag(name); tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ensureTags 确保标签存在，返回标签ID
+func (s *TagService) ensureTags(tx *gorm.DB, names []string) ([]int, error) {
+	now := time.Now()
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name, CreatedAt: now})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to create tags: %v", err)
+	}
+
+	var ids []int
+	if err := tx.Model(&models.Tag{}).Where("name IN ?", names).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tags: %v", err)
+	}
+	return ids, nil
+}
+
+// AddToImages 给图片添加标签，不存在的标签会自动创建
+// 有图片超出标签上限时返回 *TagLimitError，所有修改回滚
+func (s *TagService) AddToImages(imageIDs []int, names []string) error {
+	names = NormalizeTags(names)
+	if len(imageIDs) == 0 || len(names) == 0 {
+		return nil
+	}
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		tagIDs, err := s.ensureTags(tx, names)
+		if err != nil {
+			return err
+		}
+		full, err := s.overLimit(tx, imageIDs, tagIDs)
+		if err != nil {
+			return err
+		}
+		if len(full) > 0 {
+			return &TagLimitError{ImageIDs: full}
+		}
+
+		now := time.Now()
+		links := make([]models.ImageTag, 0, len(imageIDs)*len(tagIDs))
+		for _, imageID := range imageIDs {
+			for _, tagID := range tagIDs {
+				links = append(links, models.ImageTag{ImageId: imageID, TagId: tagID, CreatedAt: now})
+			}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&links, 500).Error; err != nil {
+			return fmt.Errorf("failed to tag images: %v", err)
+		}
+		return SearchSvc.Index(tx, imageIDs)
+	})
+}
+
+// overLimit 返回添加标签后会超出上限的图片，图片上已有的标签不重复计算
+func (s *TagService) overLimit(tx *gorm.DB, imageIDs, tagIDs []int) ([]int, error) {
+	// MySQL 锁定图片行，避免并发添加标签时同时通过检查；SQLite 写事务本身是串行的
+	if tx.Dialector.Name() == "mysql" {
+		var locked []int
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Unscoped().Model(&models.Image{}).
+			Where("id IN ?", imageIDs).Pluck("id", &locked).Error; err != nil {
+			return nil, fmt.Errorf("failed to lock images: %v", err)
+		}
+	}
+
+	var counts []struct {
+		ImageId int
+		Total   int
+		Present int
+	}
+	err := tx.Model(&models.ImageTag{}).
+		Select("image_id, COUNT(*) AS total, SUM(CASE WHEN tag_id IN ? THEN 1 ELSE 0 END) AS present", tagIDs).
+		Where("image_id IN ?", imageIDs).Group("image_id").Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count image tags: %v", err)
+	}
+
+	added := make(map[int]int, len(imageIDs))
+	for _, imageID := range imageIDs {
+		added[imageID] = len(tagIDs)
+	}
+	totals := make(map[int]int, len(counts))
+	for _, count := range counts {
+		totals[count.ImageId] = count.Total
+		added[count.ImageId] = len(tagIDs) - count.Present
+	}
+
+	var full []int
+	for _, imageID := range imageIDs {
+		if totals[imageID]+added[imageID] > MaxTagsPerImage {
+			full = append(full, imageID)
+		}
+	}
+	return full, nil
+}
+
+// RemoveFromImages 移除图片上的标签，并清理不再使用的标签
+func (s *TagService) RemoveFromImages(imageIDs []int, names []string) error {
+	names = NormalizeTags(names)
+	if len(imageIDs) == 0 || len(names) == 0 {
+		return nil
+	}
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("image_id IN ? AND tag_id IN (?)", imageIDs,
+			tx.Model(&models.Tag{}).Select("id").Where("name IN ?", names)).
+			Delete(&models.ImageTag{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to untag images: %v", err)
+		}
+		if err := s.deleteUnused(tx); err != nil {
+			return err
+		}
+		return SearchSvc.Index(tx, imageIDs)
+	})
+}
+
+// Rename 重命名标签，新名称已存在时返回 ErrTagExists（应使用合并）
+func (s *TagService) Rename(tagID int, name string) error {
+	db := database.GetDB().DB
+	var count int64
+	db.Model(&models.Tag{}).Where("name = ? AND id <> ?", name, tagID).Count(&count)
+	if count > 0 {
+		return ErrTagExists
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Tag{}).Where("id = ?", tagID).Update("name", name).Error; err != nil {
+			return err
+		}
+		return SearchSvc.Index(tx, s.taggedImages(tx, tagID))
+	})
+}
+
+// Merge 把多个标签合并到目标标签，源标签随后被删除
+func (s *TagService) Merge(sourceIDs []int, targetID int) error {
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		var links []models.ImageTag
+		if err := tx.Where("tag_id IN ?", sourceIDs).Find(&links).Error; err != nil {
+			return err
+		}
+		if len(links) > 0 {
+			now := time.Now()
+			merged := make([]models.ImageTag, 0, len(links))
+			for _, link := range links {
+				merged = append(merged, models.ImageTag{ImageId: link.ImageId, TagId: targetID, CreatedAt: now})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&merged, 500).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("tag_id IN ?", sourceIDs).Delete(&models.ImageTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", sourceIDs).Delete(&models.Tag{}).Error; err != nil {
+			return err
+		}
+		return SearchSvc.Index(tx, s.taggedImages(tx, targetID))
+	})
+}
+
+// Delete 删除标签及其所有关联
+func (s *TagService) Delete(tagID int) error {
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		imageIDs := s.taggedImages(tx, tagID)
+		if err := tx.Where("tag_id = ?", tagID).Delete(&models.ImageTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Tag{}, tagID).Error; err != nil {
+			return err
+		}
+		return SearchSvc.Index(tx, imageIDs)
+	})
+}
+
+// taggedImages 返回带有该标签的图片ID
+func (s *TagService) taggedImages(tx *gorm.DB, tagID int) []int {
+	var ids []int
+	tx.Model(&models.ImageTag{}).Where("tag_id = ?", tagID).Pluck("image_id", &ids)
+	return ids
+}
+
+// Suggest 按前缀联想标签，images 限定统计范围（只返回这些图片上用到的标签）
+func (s *TagService) Suggest(images *gorm.DB, prefix string, limit int) ([]TagSuggestion, error) {
+	query := database.GetDB().DB.Model(&models.Tag{}).
+		Select("tags.id, tags.name, COUNT(image_tags.image_id) as count").
+		Joins("JOIN image_tags ON image_tags.tag_id = tags.id").
+		Where("image_tags.image_id IN (?)", images.Select("id")).
+		Group("tags.id, tags.name").
+		Order("count DESC, tags.name ASC").
+		Limit(limit)
+	if prefix = NormalizeTag(prefix); prefix != "" {
+		query = query.Where("tags.name LIKE ? ESCAPE '!'", EscapeLike(prefix)+"%")
+	}
+
+	var suggestions []TagSuggestion
+	if err := query.Scan(&suggestions).Error; err != nil {
+		return nil, fmt.Errorf("failed to suggest tags: %v", err)
+	}
+	return suggestions, nil
+}
+
+// Attach 为图片列表填充标签
+func (s *TagService) Attach(images []models.Image) {
+	s.attach(database.GetDB().DB, images)
+}
+
+// attach 使用指定的连接（可以是事务）填充标签
+func (s *TagService) attach(db *gorm.DB, images []models.Image) {
+	if len(images) == 0 {
+		return
+	}
+	ids := make([]int, 0, len(images))
+	for _, image := range images {
+		ids = append(ids, image.Id)
+	}
+
+	var rows []struct {
+		ImageId int
+		Name    string
+	}
+	db.Model(&models.ImageTag{}).
+		Select("image_tags.image_id, tags.name").
+		Joins("JOIN tags ON tags.id = image_tags.tag_id").
+		Where("image_tags.image_id IN ?", ids).
+		Scan(&rows)
+
+	byImage := make(map[int][]string, len(images))
+	for _, row := range rows {
+		byImage[row.ImageId] = append(byImage[row.ImageId], row.Name)
+	}
+	for i := range images {
+		tags := byImage[images[i].Id]
+		sort.Strings(tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		images[i].Tags = tags
+	}
+}
+
+// DetachImages 图片被删除时清理其标签关联
+func (s *TagService) DetachImages(tx *gorm.DB, imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("image_id IN ?", imageIDs).Delete(&models.ImageTag{}).Error; err != nil {
+		return err
+	}
+	return s.deleteUnused(tx)
+}
+
+// deleteUnused 删除没有任何图片使用的标签
+func (s *TagService) deleteUnused(tx *gorm.DB) error {
+	return tx.Where("id NOT IN (?)", tx.Model(&models.ImageTag{}).Select("tag_id")).Delete(&models.Tag{}).Error
+}
+
+// EscapeLike 转义LIKE中的通配符，配合 ESCAPE '!' 使用（SQLite和MySQL通用）
+func EscapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
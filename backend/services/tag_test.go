@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"oneimg/backend/models"
+)
+
+func TestAddToImagesEnforcesLimit(t *testing.T) {
+	db := initTestDB(t)
+
+	images := []models.Image{
+		{Url: "/uploads/full.webp", FileName: "full.webp", CreatedAt: time.Now()},
+		{Url: "/uploads/empty.webp", FileName: "empty.webp", CreatedAt: time.Now()},
+	}
+	if err := db.Create(&images).Error; err != nil {
+		t.Fatal(err)
+	}
+	full, empty := images[0].Id, images[1].Id
+
+	existing := make([]string, 0, MaxTagsPerImage-1)
+	for i := 0; i < MaxTagsPerImage-1; i++ {
+		existing = append(existing, fmt.Sprintf("tag-%02d", i))
+	}
+	if err := TagSvc.AddToImages([]int{full}, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已有的标签不计入新增数量：49个已有 + 1个新标签 = 50
+	if err := TagSvc.AddToImages([]int{full, empty}, []string{"tag-00", "tag-01", "new"}); err != nil {
+		t.Fatalf("expected tags to fit, got %v", err)
+	}
+
+	// 再加一个新标签会超出上限，整批回滚
+	err := TagSvc.AddToImages([]int{full, empty}, []string{"another"})
+	var limitErr *TagLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected TagLimitError, got %v", err)
+	}
+	if len(limitErr.ImageIDs) != 1 || limitErr.ImageIDs[0] != full {
+		t.Fatalf("expected only image %d to be over the limit, got %v", full, limitErr.ImageIDs)
+	}
+
+	var count int64
+	db.Model(&models.Tag{}).Where("name = ?", "another").Count(&count)
+	if count != 0 {
+		t.Fatal("expected the new tag to be rolled back")
+	}
+	db.Model(&models.ImageTag{}).Where("image_id = ?", empty).Count(&count)
+	if count != 3 {
+		t.Fatalf("expected image %d to keep 3 tags, got %d", empty, count)
+	}
+	db.Model(&models.ImageTag{}).Where("image_id = ?", full).Count(&count)
+	if count != MaxTagsPerImage {
+		t.Fatalf("expected image %d to have %d tags, got %d", full, MaxTagsPerImage, count)
+	}
+}
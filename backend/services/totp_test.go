@@ -0,0 +1,136 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RFC 6238 附录B的SHA1测试密钥 "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// RFC 6238 附录B的SHA1测试向量（取8位结果的后6位）
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, v := range rfc6238Vectors {
+		if got := hotp(key, v.unix/totpPeriod); got != v.code {
+			t.Errorf("T=%d: expected %s, got %s", v.unix, v.code, got)
+		}
+	}
+}
+
+func TestValidateTOTPVectors(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		counter, ok := validateTOTPAt(rfc6238Secret, v.code, 0, now)
+		if !ok || counter != v.unix/totpPeriod {
+			t.Errorf("T=%d: expected counter %d, got %d (ok=%v)", v.unix, v.unix/totpPeriod, counter, ok)
+		}
+	}
+
+	// 小写密钥和首尾空白同样可以通过
+	if _, ok := validateTOTPAt(strings.ToLower(rfc6238Secret), " 005924 ", 0, time.Unix(1234567890, 0)); !ok {
+		t.Error("expected lower-case secret and padded code to validate")
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	code := "005924"
+
+	for _, offset := range []time.Duration{-totpPeriod * time.Second, totpPeriod * time.Second} {
+		if _, ok := validateTOTPAt(rfc6238Secret, code, 0, at.Add(offset)); !ok {
+			t.Errorf("expected code to validate with a clock offset of %v", offset)
+		}
+	}
+	for _, offset := range []time.Duration{-2 * totpPeriod * time.Second, 2 * totpPeriod * time.Second} {
+		if _, ok := validateTOTPAt(rfc6238Secret, code, 0, at.Add(offset)); ok {
+			t.Errorf("expected code to be rejected with a clock offset of %v", offset)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsReplay(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	counter, ok := validateTOTPAt(rfc6238Secret, "005924", 0, at)
+	if !ok {
+		t.Fatal("expected first use to validate")
+	}
+
+	// 同一验证码在有效窗口内再次使用
+	if _, ok := validateTOTPAt(rfc6238Secret, "005924", counter, at); ok {
+		t.Fatal("expected replayed code to be rejected")
+	}
+	if _, ok := validateTOTPAt(rfc6238Secret, "005924", counter, at.Add(totpPeriod*time.Second)); ok {
+		t.Fatal("expected replayed code to be rejected in the next time step")
+	}
+
+	// 更早时间步的验证码同样无效
+	previous := hotp([]byte("12345678901234567890"), counter-1)
+	if _, ok := validateTOTPAt(rfc6238Secret, previous, counter, at); ok {
+		t.Fatal("expected code from an earlier time step to be rejected")
+	}
+
+	// 下一个时间步的新验证码可以使用
+	next := hotp([]byte("12345678901234567890"), counter+1)
+	if got, ok := validateTOTPAt(rfc6238Secret, next, counter, at.Add(totpPeriod*time.Second)); !ok || got != counter+1 {
+		t.Fatalf("expected next code to validate, got %d (ok=%v)", got, ok)
+	}
+}
+
+func TestValidateTOTPRejectsMalformed(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	for _, code := range []string{"", "00592", "0059244", "abcdef"} {
+		if _, ok := validateTOTPAt(rfc6238Secret, code, 0, at); ok {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+	if _, ok := validateTOTPAt("not base32!", "005924", 0, at); ok {
+		t.Error("expected an invalid secret to be rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d codes and %d hashes", RecoveryCodeCount, len(codes), len(hashes))
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if seen[code] {
+			t.Fatalf("duplicate recovery code %s", code)
+		}
+		seen[code] = true
+		if err := bcrypt.CompareHashAndPassword([]byte(hashes[i]), []byte(code)); err != nil {
+			t.Fatalf("recovery code %s did not match its hash", code)
+		}
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hashes[1]), []byte(codes[0])); err == nil {
+		t.Fatal("recovery code matched another code's hash")
+	}
+
+	// 用户输入的大写、去掉连字符或带空格的形式都能匹配
+	for _, input := range []string{strings.ToUpper(codes[0]), strings.ReplaceAll(codes[0], "-", ""), " " + codes[0] + " "} {
+		if err := bcrypt.CompareHashAndPassword([]byte(hashes[0]), []byte(NormalizeRecoveryCode(input))); err != nil {
+			t.Fatalf("recovery code input %q did not match its hash", input)
+		}
+	}
+}
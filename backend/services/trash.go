@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"oneimg/backend/config"
+	"oneimg/backend/database"
+	"oneimg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 回收站的清理间隔
+const trashPurgeInterval = time.Hour
+
+// TrashService 回收站服务：彻底删除图片，并定期清理超过保留期的图片
+type TrashService struct {
+	uploadPath string
+	retention  time.Duration // 0表示不自动清理
+}
+
+var TrashSvc *TrashService
+
+// InitTrashService 初始化回收站服务并启动定期清理
+func InitTrashService(cfg *config.Config) {
+	TrashSvc = &TrashService{
+		uploadPath: cfg.UploadPath,
+		retention:  cfg.TrashRetention,
+	}
+	if TrashSvc.retention <= 0 {
+		return
+	}
+
+	go func() {
+		TrashSvc.PurgeExpired()
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			TrashSvc.PurgeExpired()
+		}
+	}()
+}
+
+// PurgeAt 返回回收站中图片将被自动清理的时间，不自动清理时返回nil
+func (s *TrashService) PurgeAt(image *models.Image) *time.Time {
+	if s.retention <= 0 || !image.DeletedAt.Valid {
+		return nil
+	}
+	at := image.DeletedAt.Time.Add(s.retention)
+	return &at
+}
+
+// Destroy 彻底删除图片：先删除文件，成功后再删除记录及其关联
+// 文件删除失败时保留记录，便于之后重试
+func (s *TrashService) Destroy(image *models.Image) error {
+	if err := ImageSvc.RemoveFile(s.uploadPath, image); err != nil {
+		return err
+	}
+	return database.GetDB().DB.Transaction(func(tx *gorm.DB) error {
+		if err := ImageSvc.DetachRelations(tx, []int{image.Id}); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(image).Error; err != nil {
+			return fmt.Errorf("failed to delete image: %v", err)
+		}
+		return nil
+	})
+}
+
+// DestroyAll 彻底删除多张图片，返回成功删除的数量和遇到的第一个错误
+func (s *TrashService) DestroyAll(images []models.Image) (int, error) {
+	var firstErr error
+	destroyed := 0
+	for i := range images {
+		if err := s.Destroy(&images[i]); err != nil {
+			log.Printf("彻底删除图片 %d 失败: %v", images[i].Id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		destroyed++
+	}
+	return destroyed, firstErr
+}
+
+// PurgeExpired 清理超过保留期的回收站图片
+func (s *TrashService) PurgeExpired() {
+	var images []models.Image
+	err := database.GetDB().DB.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at <= ?", time.Now().Add(-s.retention)).
+		Find(&images).Error
+	if err != nil {
+		log.Printf("查询回收站过期图片失败: %v", err)
+		return
+	}
+	if destroyed, _ := s.DestroyAll(images); destroyed > 0 {
+		log.Printf("已从回收站清理%d张图片", destroyed)
+	}
+}
@@ -10,6 +10,33 @@ const routes = [
       public: true  // 标记为公开路由，不需要登录即可访问
     }
   },
+  {
+    path: '/register',
+    name: 'Register',
+    component: () => import('@/views/Login.vue'),
+    meta: {
+      title: '注册',
+      public: true
+    }
+  },
+  {
+    path: '/guest',
+    name: 'Guest',
+    component: () => import('@/views/Guest.vue'),
+    meta: {
+      title: '游客上传',
+      public: true
+    }
+  },
+  {
+    path: '/guest/delete/:token',
+    name: 'GuestDelete',
+    component: () => import('@/views/Guest.vue'),
+    meta: {
+      title: '删除图片',
+      public: true
+    }
+  },
   {
     path: '/',
     name: 'Home',
@@ -0,0 +1,99 @@
+// 人机验证辅助方法（登录、注册和游客上传页共用）
+
+import { sha256Digest } from './sha256.js';
+
+// 获取人机验证参数
+export const fetchCaptcha = async () => {
+    const response = await fetch('/api/captcha');
+    const result = await response.json();
+    if (!response.ok || !result.success) {
+        throw new Error(result.message || '获取验证参数失败');
+    }
+    return result.data;
+};
+
+// 内置POW：寻找nonce使 sha256(challenge:nonce) 的前导零比特数满足难度
+export const solveBuiltinPow = async ({ challenge, difficulty }) => {
+    const encoder = new TextEncoder();
+    const hasLeadingZeros = (bytes) => {
+        let remaining = difficulty;
+        for (const b of bytes) {
+            if (remaining >= 8) {
+                if (b !== 0) return false;
+                remaining -= 8;
+                continue;
+            }
+            return remaining === 0 || (b >> (8 - remaining)) === 0;
+        }
+        return true;
+    };
+
+    for (let nonce = 0; ; nonce++) {
+        const digest = await sha256Digest(encoder.encode(challenge + ':' + nonce));
+        if (hasLeadingZeros(digest)) {
+            return challenge + ':' + nonce;
+        }
+    }
+};
+
+// 动态加载验证脚本
+const loadScript = (src) => new Promise((resolve, reject) => {
+    if (document.querySelector(`script[src="${src}"]`)) {
+        resolve();
+        return;
+    }
+    const script = document.createElement('script');
+    script.src = src;
+    script.onload = resolve;
+    script.onerror = () => reject(new Error('验证脚本加载失败'));
+    document.head.appendChild(script);
+});
+
+// 第三方验证组件脚本
+const widgetScripts = {
+    remote_pow: (endpoint) => endpoint.replace(/\/$/, '') + '/static/js/pow.min.js',
+    hcaptcha: () => 'https://js.hcaptcha.com/1/api.js?render=explicit',
+    turnstile: () => 'https://challenges.cloudflare.com/turnstile/v0/api.js?render=explicit',
+};
+
+// 在容器中渲染验证组件，用户完成验证后返回token
+export const renderCaptchaWidget = async (container, { provider, endpoint, site_key: siteKey }) => {
+    const scriptUrl = widgetScripts[provider];
+    if (!scriptUrl) {
+        throw new Error('前端暂不支持该验证方式: ' + provider);
+    }
+    await loadScript(scriptUrl(endpoint || ''));
+
+    container.innerHTML = '';
+    return new Promise((resolve, reject) => {
+        if (provider === 'hcaptcha') {
+            window.hcaptcha.render(container, { sitekey: siteKey, callback: resolve });
+            return;
+        }
+        if (provider === 'turnstile') {
+            window.turnstile.render(container, { sitekey: siteKey, callback: resolve });
+            return;
+        }
+        const widget = document.createElement('pow-widget');
+        widget.setAttribute('data-pow-api-endpoint', endpoint);
+        widget.addEventListener('solve', (e) => resolve(e.detail.token));
+        widget.addEventListener('error', () => reject(new Error('验证失败，请重试')));
+        container.appendChild(widget);
+    });
+};
+
+// 获取一次性验证token：无需验证时返回空字符串，内置POW自动计算，其他方式在容器中渲染组件
+// container 可以是元素，也可以是返回元素的函数（只在需要渲染组件时调用，如打开弹窗）
+export const getCaptchaToken = async (container) => {
+    const captcha = await fetchCaptcha();
+    switch (captcha.provider) {
+        case 'none':
+            return '';
+        case 'pow':
+            return solveBuiltinPow(captcha);
+        default: {
+            const target = typeof container === 'function' ? await container() : container;
+            return renderCaptchaWidget(target, captcha);
+        }
+    }
+};
@@ -73,6 +73,6 @@ echo 按 Ctrl+C 停止服务
 echo ========================================
 echo.
 
-go run main.go
+go run -tags sqlite_fts5 main.go
 
 pause
\ No newline at end of file
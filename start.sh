@@ -69,4 +69,4 @@ echo "按 Ctrl+C 停止服务"
 echo "========================================"
 echo ""
 
-go run main.go
\ No newline at end of file
+go run -tags sqlite_fts5 main.go
\ No newline at end of file